@@ -0,0 +1,123 @@
+package worm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+)
+
+// Aggregator follows a log and keeps rolling statistics about it, so
+// dashboards can share one reader instead of each rescanning the log
+type Aggregator struct {
+	lg     Logger
+	window time.Duration
+
+	mu        sync.Mutex
+	recent    []arrival // records inside the window, oldest first
+	records   int64
+	coalesced int64 // as in Report.Coalesced
+	last      event.Record
+	sessions  map[string]time.Time
+}
+
+type arrival struct {
+	at  time.Time
+	typ string
+}
+
+// LiveStats is a snapshot of an Aggregator
+type LiveStats struct {
+	Records   int64
+	Rate      map[string]float64 // records per second by type over the window
+	Coalesce  float64            // Coalesced records over Records, 1 if nothing merges
+	Sessions  []string           // IDs of sessions started and not ended
+	Window    time.Duration
+	Generated time.Time
+}
+
+// NewAggregator returns an Aggregator for lg computing rates over window
+func NewAggregator(lg Logger, window time.Duration) *Aggregator {
+	return &Aggregator{lg: lg, window: window, sessions: make(map[string]time.Time)}
+}
+
+// Run follows the log from the start until ctx is done
+func (a *Aggregator) Run(ctx context.Context) error {
+	return Follow(ctx, a.lg, 0, func(_ int64, v event.Record) error {
+		a.add(v, time.Now())
+		return nil
+	})
+}
+
+func (a *Aggregator) add(v event.Record, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	at := recordTime(v)
+	if at.IsZero() {
+		at = now
+	}
+	a.records++
+	a.recent = append(a.recent, arrival{at, fmt.Sprintf("%T", inner(v))})
+	a.prune(now)
+	if a.last != nil {
+		if next := a.last.Coalesce(v); next != nil {
+			a.last = next
+		} else {
+			a.last = v
+			a.coalesced++
+		}
+	} else {
+		a.last = v
+		a.coalesced++
+	}
+	switch m := inner(v).(type) {
+	case *SessionStart:
+		a.sessions[m.ID] = m.At
+	case *SessionEnd:
+		delete(a.sessions, m.ID)
+	}
+}
+
+// prune drops arrivals older than the window. Arrivals are only roughly in
+// time order, so it keeps the order recorded and filters.
+func (a *Aggregator) prune(now time.Time) {
+	cut := now.Add(-a.window)
+	keep := a.recent[:0]
+	for _, r := range a.recent {
+		if r.at.After(cut) {
+			keep = append(keep, r)
+		}
+	}
+	a.recent = keep
+}
+
+// Stats returns the current statistics
+func (a *Aggregator) Stats() LiveStats {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prune(now)
+	s := LiveStats{
+		Records:   a.records,
+		Rate:      make(map[string]float64),
+		Coalesce:  1,
+		Window:    a.window,
+		Generated: now,
+	}
+	if a.window > 0 {
+		for _, r := range a.recent {
+			s.Rate[r.typ] += 1 / a.window.Seconds()
+		}
+	}
+	if a.records > 0 {
+		s.Coalesce = float64(a.coalesced) / float64(a.records)
+	}
+	for id := range a.sessions {
+		s.Sessions = append(s.Sessions, id)
+	}
+	sort.Strings(s.Sessions)
+	return s
+}
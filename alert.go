@@ -0,0 +1,136 @@
+package worm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+)
+
+// Alert reports an integrity failure: a log that no longer matches its
+// anchors, or a record that does not match its checksum
+type Alert struct {
+	Op  string // what detected it, e.g. "verify anchors" or "copy"
+	N   int64  // the record or anchor involved
+	Err string
+	At  time.Time
+}
+
+// Coalesce returns nil, alerts are never merged
+func (a *Alert) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Time returns when the failure was detected
+func (a *Alert) Time() time.Time {
+	return a.At
+}
+
+// AlertHook is notified of integrity failures
+type AlertHook interface {
+	Alert(Alert) error
+}
+
+// AlertFunc is an AlertHook calling itself
+type AlertFunc func(Alert) error
+
+// Alert calls f(a)
+func (f AlertFunc) Alert(a Alert) error {
+	return f(a)
+}
+
+// AlarmLog returns a hook appending each alert to lg, a log set aside for
+// alarms
+func AlarmLog(lg Logger) AlertHook {
+	return AlertFunc(func(a Alert) error {
+		return lg.Write(&a)
+	})
+}
+
+// Webhook returns a hook POSTing each alert to url as JSON
+func Webhook(url string) AlertHook {
+	return AlertFunc(func(a Alert) error {
+		return postJSON(url, a)
+	})
+}
+
+// alertBacklog is the number of alerts queued for slow hooks before
+// further alerts are dropped
+const alertBacklog = 64
+
+var alertHooks struct {
+	sync.RWMutex
+	list  []AlertHook
+	queue chan Alert
+}
+
+// OnAlert adds h to the hooks notified whenever VerifyAnchors, VerifyDeep,
+// Copy, Unbundle or a Blobs read detects corruption or tampering. Hooks run
+// in the background, one alert at a time in the order detected, so a slow
+// hook never blocks the call that found the problem. Alerts arriving while
+// the hooks are far behind are logged and dropped, and hook errors are
+// logged and otherwise ignored.
+func OnAlert(h AlertHook) {
+	alertHooks.Lock()
+	defer alertHooks.Unlock()
+	alertHooks.list = append(alertHooks.list, h)
+	if alertHooks.queue == nil {
+		alertHooks.queue = make(chan Alert, alertBacklog)
+		go deliverAlerts(alertHooks.queue)
+	}
+}
+
+// alert queues err, found by op at record n, for the hooks and returns err
+func alert(op string, n int64, err error) error {
+	alertHooks.RLock()
+	q := alertHooks.queue
+	alertHooks.RUnlock()
+	if q == nil {
+		return err
+	}
+	select {
+	case q <- Alert{Op: op, N: n, Err: err.Error(), At: time.Now()}:
+	default:
+		log.Printf("worm: alert hooks behind, dropped alert: %s: %v", op, err)
+	}
+	return err
+}
+
+func deliverAlerts(q <-chan Alert) {
+	for a := range q {
+		alertHooks.RLock()
+		list := alertHooks.list
+		alertHooks.RUnlock()
+		for _, h := range list {
+			if err := h.Alert(a); err != nil {
+				log.Printf("worm: alert hook: %v", err)
+			}
+		}
+	}
+}
+
+// postClient bounds each request made by Webhook and HTTPSink, so a hung
+// endpoint cannot stall the caller forever
+var postClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON POSTs v to url as JSON, failing on a non-2xx status
+func postJSON(url string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := postClient.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post %s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package worm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/as/event"
+)
+
+// Report describes the contents of a log, for planning retention and
+// compaction settings
+type Report struct {
+	Records int64
+	Bytes   int64            // total encoded size
+	Types   map[string]int64 // records by Go type, inside any envelope
+
+	// Encoded record sizes in bytes
+	P50, P95, Max int
+
+	// Rate counts the records carrying a time per bucket, oldest first
+	Rate []Bucket
+
+	// Coalesced is the number of records left if every run of adjacent
+	// coalescable records were merged, an estimate of what a Coalescer
+	// in front of the log would have saved
+	Coalesced int64
+}
+
+// Bucket counts the records written in the period starting at Start
+type Bucket struct {
+	Start time.Time
+	Count int64
+}
+
+// Analyze reads every record of lg and reports on them. Records carrying a
+// time are counted into buckets of the given width; a width of zero skips
+// the rate report.
+func Analyze(lg Logger, width time.Duration) (*Report, error) {
+	r := &Report{Types: make(map[string]int64)}
+	var (
+		sizes []int
+		rate  = make(map[int64]int64)
+		last  event.Record
+	)
+	n := lg.Len()
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return r, err
+		}
+		b, err := encode(v)
+		if err != nil {
+			return r, err
+		}
+		r.Records++
+		r.Bytes += int64(len(b))
+		r.Types[fmt.Sprintf("%T", inner(v))]++
+		sizes = append(sizes, len(b))
+		if t := recordTime(v); width > 0 && !t.IsZero() {
+			rate[t.UnixNano()/int64(width)]++
+		}
+		if last != nil {
+			if next := last.Coalesce(v); next != nil {
+				last = next
+				continue
+			}
+		}
+		r.Coalesced++
+		last = v
+	}
+	if len(sizes) > 0 {
+		sort.Ints(sizes)
+		r.P50 = sizes[(len(sizes)-1)*50/100]
+		r.P95 = sizes[(len(sizes)-1)*95/100]
+		r.Max = sizes[len(sizes)-1]
+	}
+	for k, c := range rate {
+		r.Rate = append(r.Rate, Bucket{Start: time.Unix(0, k*int64(width)), Count: c})
+	}
+	sort.Slice(r.Rate, func(i, j int) bool { return r.Rate[i].Start.Before(r.Rate[j].Start) })
+	return r, nil
+}
+
+// String formats the report for people
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "records %d bytes %d\n", r.Records, r.Bytes)
+	fmt.Fprintf(&b, "size p50 %d p95 %d max %d\n", r.P50, r.P95, r.Max)
+	if r.Records > 0 {
+		fmt.Fprintf(&b, "coalesced %d (%.1f%%)\n", r.Coalesced, 100*float64(r.Coalesced)/float64(r.Records))
+	}
+	types := make([]string, 0, len(r.Types))
+	for t := range r.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(&b, "type %s %d\n", t, r.Types[t])
+	}
+	for _, k := range r.Rate {
+		fmt.Fprintf(&b, "rate %s %d\n", k.Start.UTC().Format(time.RFC3339), k.Count)
+	}
+	return b.String()
+}
+
+// inner returns the record wrapped by any envelopes around v
+func inner(v event.Record) event.Record {
+	for {
+		switch e := v.(type) {
+		case *Envelope:
+			v = e.Record
+		case *Stamped:
+			v = e.Record
+		case *Sequenced:
+			v = e.Record
+		case *Intent:
+			v = e.Record
+		default:
+			return v
+		}
+	}
+}
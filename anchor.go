@@ -0,0 +1,226 @@
+package worm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+)
+
+// ErrTampered is returned when a log no longer matches an anchor published
+// for it
+var ErrTampered = errors.New("log does not match anchor")
+
+// Hash is the head of a log's hash chain. The head of an empty log is all
+// zeros and each record extends it as SHA-256(head || record checksum).
+type Hash [sha256.Size]byte
+
+// MarshalText encodes h as hex
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h[:])), nil
+}
+
+// UnmarshalText decodes hex into h
+func (h *Hash) UnmarshalText(b []byte) error {
+	if hex.DecodedLen(len(b)) != len(h) {
+		return fmt.Errorf("bad hash length: %d", len(b))
+	}
+	_, err := hex.Decode(h[:], b)
+	return err
+}
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// extend returns the chain head after appending v
+func (h Hash) extend(v event.Record) (Hash, error) {
+	sum, err := checksum(v)
+	if err != nil {
+		return h, err
+	}
+	return sha256.Sum256(append(h[:], sum[:]...)), nil
+}
+
+// Head returns the hash chain head over the first n records of lg
+func Head(lg Logger, n int64) (h Hash, err error) {
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return h, err
+		}
+		if h, err = h.extend(v); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// Anchor records the chain head of a log's first N records at a point in
+// time. Anchors published somewhere the log's host cannot rewrite make later
+// tampering with those records detectable.
+type Anchor struct {
+	N    int64
+	Head Hash
+	At   time.Time
+}
+
+// Coalesce returns nil, anchors are never merged
+func (a *Anchor) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Time returns when the anchor was taken
+func (a *Anchor) Time() time.Time {
+	return a.At
+}
+
+// AnchorSink publishes anchors
+type AnchorSink interface {
+	Publish(Anchor) error
+}
+
+// AnchorFunc is an AnchorSink calling itself
+type AnchorFunc func(Anchor) error
+
+// Publish calls f(a)
+func (f AnchorFunc) Publish(a Anchor) error {
+	return f(a)
+}
+
+// LogSink returns a sink appending each anchor to lg, such as another worm
+// log on a different machine
+func LogSink(lg Logger) AnchorSink {
+	return AnchorFunc(func(a Anchor) error {
+		return lg.Write(&a)
+	})
+}
+
+// WriterSink returns a sink writing each anchor to w as a line of JSON
+func WriterSink(w io.Writer) AnchorSink {
+	var mu sync.Mutex
+	return AnchorFunc(func(a Anchor) error {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, err = w.Write(append(b, '\n'))
+		return err
+	})
+}
+
+// HTTPSink returns a sink POSTing each anchor to url as JSON
+func HTTPSink(url string) AnchorSink {
+	return AnchorFunc(func(a Anchor) error {
+		if err := postJSON(url, a); err != nil {
+			return fmt.Errorf("publish anchor: %w", err)
+		}
+		return nil
+	})
+}
+
+// Anchorer publishes the chain head of a log to a sink
+type Anchorer struct {
+	lg   Logger
+	sink AnchorSink
+
+	mu   sync.Mutex
+	n    int64 // records covered by head
+	head Hash
+}
+
+// NewAnchorer returns an Anchorer for lg publishing to sink
+func NewAnchorer(lg Logger, sink AnchorSink) *Anchorer {
+	return &Anchorer{lg: lg, sink: sink}
+}
+
+// Anchor extends the chain over any records written since the last call
+// and publishes the current head
+func (a *Anchorer) Anchor() (Anchor, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for end := a.lg.Len(); a.n < end; a.n++ {
+		v, err := a.lg.ReadAt(a.n)
+		if err != nil {
+			return Anchor{}, err
+		}
+		if a.head, err = a.head.extend(v); err != nil {
+			return Anchor{}, err
+		}
+	}
+	an := Anchor{N: a.n, Head: a.head, At: time.Now()}
+	return an, a.sink.Publish(an)
+}
+
+// Run publishes an anchor every period until ctx is done. Publishing
+// errors are passed to fail, if not nil, and do not stop the loop.
+func (a *Anchorer) Run(ctx context.Context, period time.Duration, fail func(error)) error {
+	tick := time.NewTicker(period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			if _, err := a.Anchor(); err != nil && fail != nil {
+				fail(err)
+			}
+		}
+	}
+}
+
+// ReadAnchors returns the anchors stored in lg, such as a log written by
+// LogSink. Other records are skipped.
+func ReadAnchors(lg Logger) ([]Anchor, error) {
+	var list []Anchor
+	for n := int64(0); n < lg.Len(); n++ {
+		v, err := lg.ReadAt(n)
+		if err != nil {
+			return list, err
+		}
+		if a, ok := inner(v).(*Anchor); ok {
+			list = append(list, *a)
+		}
+	}
+	return list, nil
+}
+
+// VerifyAnchors checks lg against anchors published for it. It returns an
+// error wrapping ErrTampered for the first anchor whose head no longer
+// matches or whose records are missing.
+func VerifyAnchors(lg Logger, anchors []Anchor) error {
+	anchors = append([]Anchor(nil), anchors...)
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].N < anchors[j].N })
+	var (
+		n    int64
+		head Hash
+	)
+	for _, an := range anchors {
+		if an.N > lg.Len() {
+			return alert("verify anchors", an.N, fmt.Errorf("anchor at record %d: log has %d records: %w", an.N, lg.Len(), ErrTampered))
+		}
+		for ; n < an.N; n++ {
+			v, err := lg.ReadAt(n)
+			if err != nil {
+				return err
+			}
+			if head, err = head.extend(v); err != nil {
+				return err
+			}
+		}
+		if head != an.Head {
+			return alert("verify anchors", an.N, fmt.Errorf("anchor at record %d: %w", an.N, ErrTampered))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package worm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownBackend is returned by Open for a URL scheme no backend was
+// registered for
+var ErrUnknownBackend = errors.New("unknown backend")
+
+// Backend opens logs stored by one kind of storage
+type Backend interface {
+	// Open opens the log named by u, whose scheme selected the backend
+	Open(u *url.URL) (Logger, error)
+}
+
+// BackendFunc is a Backend calling itself
+type BackendFunc func(u *url.URL) (Logger, error)
+
+// Open calls f(u)
+func (f BackendFunc) Open(u *url.URL) (Logger, error) {
+	return f(u)
+}
+
+var backends = struct {
+	sync.RWMutex
+	m map[string]Backend
+}{m: map[string]Backend{
+	"mem": BackendFunc(func(*url.URL) (Logger, error) { return NewLogger(), nil }),
+}}
+
+// Register makes a backend available to Open under the URL scheme name.
+// It is meant to be called from the init function of the package providing
+// the backend, and panics if name is already registered.
+func Register(name string, b Backend) {
+	backends.Lock()
+	defer backends.Unlock()
+	if _, dup := backends.m[name]; dup {
+		panic("worm: Register called twice for backend " + name)
+	}
+	backends.m[name] = b
+}
+
+// Backends returns the registered backend names, sorted
+func Backends() []string {
+	backends.RLock()
+	defer backends.RUnlock()
+	list := make([]string, 0, len(backends.m))
+	for name := range backends.m {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Open opens the log named by rawurl with the backend registered for its
+// scheme, e.g. "mem:" for a new in-memory log
+func Open(rawurl string) (Logger, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	backends.RLock()
+	b, ok := backends.m[u.Scheme]
+	backends.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w %q", rawurl, ErrUnknownBackend, u.Scheme)
+	}
+	return b.Open(u)
+}
@@ -0,0 +1,126 @@
+package worm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// BackupProgress reports how far a Backup has got
+type BackupProgress struct {
+	Shipped int64 // records of the source present in the destination
+	Total   int64 // records in the source
+	Live    bool  // the bulk copy is done and new records are shipped as written
+	Paused  bool
+}
+
+// Backup keeps a copy of a log in another logger, such as one on a
+// different machine
+type Backup struct {
+	src, dst Logger
+
+	mu      sync.Mutex
+	shipped int64
+	live    bool
+	resume  chan struct{} // not nil while paused
+}
+
+// NewBackup returns a backup of src into dst
+func NewBackup(src, dst Logger) *Backup {
+	return &Backup{src: src, dst: dst}
+}
+
+// BackupTo copies src into remote and keeps shipping new records until ctx
+// is done. It is NewBackup(src, remote).Run(ctx).
+func BackupTo(ctx context.Context, src, remote Logger) error {
+	return NewBackup(src, remote).Run(ctx)
+}
+
+// Run copies the records of the source missing from the destination in
+// bulk, then follows the source and ships each new record as it is written,
+// until ctx is done or a copy fails. Records are verified as by Copy. A
+// destination already holding a prefix of the source, e.g. from an earlier
+// Run, is continued rather than copied again.
+func (b *Backup) Run(ctx context.Context) error {
+	next := b.dst.Len()
+	if end := b.src.Len(); next > end {
+		return fmt.Errorf("backup: destination has %d records, source %d", next, end)
+	}
+	b.setShipped(next, false)
+	for end := b.src.Len(); next < end; end = b.src.Len() {
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+		to := next + chunk
+		if to > end {
+			to = end
+		}
+		n, err := Copy(ctx, b.dst, b.src, next, to)
+		next += n
+		b.setShipped(next, false)
+		if err != nil {
+			return err
+		}
+	}
+	b.setShipped(next, true)
+	return Follow(ctx, b.src, next, func(n int64, _ event.Record) error {
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+		if _, err := Copy(ctx, b.dst, b.src, n, n+1); err != nil {
+			return err
+		}
+		b.setShipped(n+1, true)
+		return nil
+	})
+}
+
+func (b *Backup) setShipped(n int64, live bool) {
+	b.mu.Lock()
+	b.shipped, b.live = n, live
+	b.mu.Unlock()
+}
+
+// wait blocks while the backup is paused
+func (b *Backup) wait(ctx context.Context) error {
+	b.mu.Lock()
+	c := b.resume
+	b.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c:
+		return nil
+	}
+}
+
+// Pause stops shipping records after the one in progress
+func (b *Backup) Pause() {
+	b.mu.Lock()
+	if b.resume == nil {
+		b.resume = make(chan struct{})
+	}
+	b.mu.Unlock()
+}
+
+// Resume continues a paused backup
+func (b *Backup) Resume() {
+	b.mu.Lock()
+	if b.resume != nil {
+		close(b.resume)
+		b.resume = nil
+	}
+	b.mu.Unlock()
+}
+
+// Progress reports how far the backup has got
+func (b *Backup) Progress() BackupProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BackupProgress{Shipped: b.shipped, Total: b.src.Len(), Live: b.live, Paused: b.resume != nil}
+}
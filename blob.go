@@ -0,0 +1,109 @@
+package worm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// ErrNoBlob is returned by a BlobStore for an unknown hash
+var ErrNoBlob = errors.New("blob not found")
+
+// BlobStore stores payloads by the SHA-256 of their contents
+type BlobStore interface {
+	Put(sum Hash, b []byte) error
+	Get(sum Hash) ([]byte, error)
+}
+
+// NewMemBlobs returns a BlobStore kept in memory
+func NewMemBlobs() BlobStore {
+	return &memBlobs{m: make(map[Hash][]byte)}
+}
+
+type memBlobs struct {
+	mu sync.RWMutex
+	m  map[Hash][]byte
+}
+
+func (s *memBlobs) Put(sum Hash, b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[sum]; !ok {
+		s.m[sum] = append([]byte(nil), b...)
+	}
+	return nil
+}
+
+func (s *memBlobs) Get(sum Hash) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.m[sum]
+	if !ok {
+		return nil, fmt.Errorf("blob %s: %w", sum, ErrNoBlob)
+	}
+	return b, nil
+}
+
+// BlobRef is the record stored in place of a payload moved to a BlobStore
+type BlobRef struct {
+	Sum  Hash
+	Size int
+}
+
+// Coalesce returns nil, blob references are never merged
+func (r *BlobRef) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Blobs is a logger storing large Data payloads once in a BlobStore and
+// references to them in the log. Reads resolve references, so readers see
+// the Data records as written.
+type Blobs struct {
+	Logger
+
+	store BlobStore
+	min   int
+}
+
+// NewBlobs wraps lg, moving Data payloads of at least min bytes to store
+func NewBlobs(lg Logger, store BlobStore, min int) *Blobs {
+	return &Blobs{Logger: lg, store: store, min: min}
+}
+
+// Write writes v to the tail of the log, storing its payload in the blob
+// store if v is a large enough Data record
+func (l *Blobs) Write(v event.Record) error {
+	d, ok := v.(Data)
+	if !ok || len(d) < l.min {
+		return l.Logger.Write(v)
+	}
+	sum := Hash(sha256.Sum256(d))
+	if err := l.store.Put(sum, d); err != nil {
+		return err
+	}
+	return l.Logger.Write(&BlobRef{Sum: sum, Size: len(d)})
+}
+
+// ReadAt returns the record at index n, resolving a blob reference to the
+// Data record it replaced
+func (l *Blobs) ReadAt(n int64) (event.Record, error) {
+	v, err := l.Logger.ReadAt(n)
+	if err != nil {
+		return v, err
+	}
+	r, ok := v.(*BlobRef)
+	if !ok {
+		return v, nil
+	}
+	b, err := l.store.Get(r.Sum)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != r.Size || sha256.Sum256(b) != r.Sum {
+		return nil, alert("read blob", n, fmt.Errorf("blob %s: %w", r.Sum, ErrChecksum))
+	}
+	return Data(b), nil
+}
@@ -0,0 +1,139 @@
+package worm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Manifest describes the log in a bundle
+type Manifest struct {
+	Records int64
+	Head    Hash // chain head over the records
+	Created time.Time
+}
+
+// Bundle writes a frozen snapshot of lg to dst as a gzipped tar archive for
+// attaching to bug reports. The archive holds manifest.json, records.jsonl
+// with one record per line encoded by reg, and report.txt from Analyze.
+// Records whose type is not registered in reg fail the bundle.
+func Bundle(dst io.Writer, lg Logger, reg *Registry) error {
+	lg = View(lg)
+	var recs bytes.Buffer
+	var head Hash
+	for at := int64(0); at < lg.Len(); at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return err
+		}
+		b, err := reg.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("bundle record %d: %w", at, err)
+		}
+		recs.Write(append(b, '\n'))
+		if head, err = head.extend(v); err != nil {
+			return err
+		}
+	}
+	man, err := json.MarshalIndent(Manifest{Records: lg.Len(), Head: head, Created: time.Now()}, "", "\t")
+	if err != nil {
+		return err
+	}
+	report, err := Analyze(lg, time.Minute)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"manifest.json", man},
+		{"records.jsonl", recs.Bytes()},
+		{"report.txt", []byte(report.String())},
+	} {
+		h := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Unbundle reads a bundle written by Bundle into a new in-memory log,
+// decoding records with reg. It returns an error wrapping ErrTampered if
+// the records do not match the manifest.
+func Unbundle(src io.Reader, reg *Registry) (Logger, *Manifest, error) {
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+	var (
+		man  *Manifest
+		recs []byte
+	)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch h.Name {
+		case "manifest.json":
+			man = new(Manifest)
+			if err := json.NewDecoder(tr).Decode(man); err != nil {
+				return nil, nil, fmt.Errorf("bundle manifest: %w", err)
+			}
+		case "records.jsonl":
+			if recs, err = io.ReadAll(tr); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if man == nil {
+		return nil, nil, fmt.Errorf("bundle has no manifest")
+	}
+	lg := NewLogger()
+	var head Hash
+	dec := json.NewDecoder(bytes.NewReader(recs))
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("bundle record %d: %w", lg.Len(), err)
+		}
+		v, err := reg.Unmarshal(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bundle record %d: %w", lg.Len(), err)
+		}
+		if head, err = head.extend(v); err != nil {
+			return nil, nil, err
+		}
+		if err = lg.Write(v); err != nil {
+			return nil, nil, err
+		}
+	}
+	if lg.Len() != man.Records || head != man.Head {
+		return lg, man, alert("unbundle", lg.Len(), fmt.Errorf("bundle records: %w", ErrTampered))
+	}
+	return lg, man, nil
+}
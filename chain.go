@@ -0,0 +1,126 @@
+package worm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// ErrNoReplicas is returned by a Chain with no nodes left
+var ErrNoReplicas = errors.New("chain has no replicas")
+
+// Chain replicates a log across a chain of loggers. A write is passed from
+// the head to the tail in order and acknowledged once the tail has it, and
+// reads are served by the tail, so a reader only ever sees records every
+// node holds. A node whose Write fails while others succeed is dropped and
+// the chain closes up around it.
+type Chain struct {
+	mu    sync.RWMutex
+	nodes []Logger
+}
+
+// NewChain returns a chain replicating to nodes, head first. The nodes
+// should hold identical logs.
+func NewChain(nodes ...Logger) *Chain {
+	return &Chain{nodes: append([]Logger(nil), nodes...)}
+}
+
+// Write passes v down the chain and returns once the tail has written it.
+// If every node rejects v the error is returned and the chain is left as
+// is, since the record itself is at fault; otherwise the nodes that failed
+// are dropped.
+func (c *Chain) Write(v event.Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.nodes) == 0 {
+		return ErrNoReplicas
+	}
+	var (
+		live []Logger
+		last error
+	)
+	for _, lg := range c.nodes {
+		if err := lg.Write(v); err != nil {
+			last = err
+			continue
+		}
+		live = append(live, lg)
+	}
+	if len(live) == 0 {
+		return last
+	}
+	c.nodes = live
+	return nil
+}
+
+// Join brings lg up to date with the tail and appends it as the new tail.
+// Writes are held while the last records are copied.
+func (c *Chain) Join(ctx context.Context, lg Logger) error {
+	for {
+		c.mu.RLock()
+		tail, err := c.tail()
+		if err != nil {
+			c.mu.RUnlock()
+			return err
+		}
+		from, to := lg.Len(), tail.Len()
+		if to-from <= chunk {
+			c.mu.RUnlock()
+			break
+		}
+		_, err = Copy(ctx, lg, tail, from, from+chunk)
+		c.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tail, err := c.tail()
+	if err != nil {
+		return err
+	}
+	if _, err = Copy(ctx, lg, tail, lg.Len(), tail.Len()); err != nil {
+		return err
+	}
+	c.nodes = append(c.nodes, lg)
+	return nil
+}
+
+// Nodes returns the number of loggers in the chain
+func (c *Chain) Nodes() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.nodes)
+}
+
+// ReadAt reads and returns log record n from the tail
+func (c *Chain) ReadAt(n int64) (event.Record, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tail, err := c.tail()
+	if err != nil {
+		return nil, err
+	}
+	return tail.ReadAt(n)
+}
+
+// Len returns the number of records acknowledged by the tail
+func (c *Chain) Len() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tail, err := c.tail()
+	if err != nil {
+		return 0
+	}
+	return tail.Len()
+}
+
+func (c *Chain) tail() (Logger, error) {
+	if len(c.nodes) == 0 {
+		return nil, ErrNoReplicas
+	}
+	return c.nodes[len(c.nodes)-1], nil
+}
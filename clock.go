@@ -0,0 +1,145 @@
+package worm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// Stamped is a record written through a Clock. It carries the writer's node
+// ID and Lamport time so logs written concurrently on several machines can
+// be merged into one consistent order.
+type Stamped struct {
+	Node   string
+	Clock  uint64
+	Record event.Record
+}
+
+// Coalesce returns nil, stamped records are never merged
+func (s *Stamped) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Clock is a logger that stamps each record with a node ID and a Lamport
+// clock before writing it to the underlying logger
+type Clock struct {
+	Logger
+
+	node string
+	mu   sync.Mutex
+	now  uint64
+}
+
+// NewClock returns a Clock for the named node writing to lg. The clock
+// starts after the highest stamp the node already wrote to lg.
+func NewClock(lg Logger, node string) *Clock {
+	c := &Clock{Logger: lg, node: node}
+	for n := int64(0); n < lg.Len(); n++ {
+		v, err := lg.ReadAt(n)
+		if err != nil {
+			continue
+		}
+		if s, ok := bare(v).(*Stamped); ok && s.Node == node && s.Clock > c.now {
+			c.now = s.Clock
+		}
+	}
+	return c
+}
+
+// Write stamps v and writes it to the tail of the log
+func (c *Clock) Write(v event.Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now++
+	return c.Logger.Write(&Stamped{Node: c.node, Clock: c.now, Record: v})
+}
+
+// Observe advances the clock past t, a stamp seen from another node. Call it
+// whenever this node acts on a record written elsewhere so causally later
+// writes sort after it.
+func (c *Clock) Observe(t uint64) {
+	c.mu.Lock()
+	if t > c.now {
+		c.now = t
+	}
+	c.mu.Unlock()
+}
+
+// Now returns the clock's current time
+func (c *Clock) Now() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Merge returns a read-only logger presenting the records of lgs in one
+// total order: by Lamport time, then node ID, then position in lgs. Records
+// that are not Stamped sort as time zero. The order is fixed when Merge is
+// called; records appended later are not included. Records are not copied.
+func Merge(lgs ...Logger) (Logger, error) {
+	type key struct {
+		ref
+		clock uint64
+		node  string
+	}
+	var keys []key
+	for i, lg := range lgs {
+		n := lg.Len()
+		for j := int64(0); j < n; j++ {
+			v, err := lg.ReadAt(j)
+			if err != nil {
+				return nil, err
+			}
+			k := key{ref: ref{i, j}}
+			if s, ok := bare(v).(*Stamped); ok {
+				k.clock, k.node = s.Clock, s.Node
+			}
+			keys = append(keys, k)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.clock != b.clock {
+			return a.clock < b.clock
+		}
+		return a.node < b.node
+	})
+	m := &refLog{lgs: lgs, refs: make([]ref, len(keys))}
+	for i, k := range keys {
+		m.refs[i] = k.ref
+	}
+	return m, nil
+}
+
+// ref locates record n of the log at index lg
+type ref struct {
+	lg int
+	n  int64
+}
+
+// refLog is a read-only log whose records live in other logs
+type refLog struct {
+	lgs  []Logger
+	refs []ref
+}
+
+func (m *refLog) Write(event.Record) error {
+	return ErrReadOnly
+}
+
+// ReadAt reads and returns log record n
+func (m *refLog) ReadAt(n int64) (event.Record, error) {
+	at := fromTail(n, int64(len(m.refs)))
+	if at < 0 || at >= int64(len(m.refs)) {
+		return nil, fmt.Errorf("bad read offset: %d", n)
+	}
+	r := m.refs[at]
+	return m.lgs[r.lg].ReadAt(r.n)
+}
+
+// Len returns the number of records
+func (m *refLog) Len() int64 {
+	return int64(len(m.refs))
+}
@@ -1,10 +1,18 @@
 package worm
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
 	"time"
 	"github.com/as/event"
 )
 
+// ErrClosed is returned by a Coalescer used after Close
+var ErrClosed = errors.New("coalescer closed")
+
 // Coalescer coalesces logs written to it until the deadband expires. After
 // expiration, the coalesced log is flushed to the underlying logger upon
 // the next call to Write().
@@ -16,23 +24,86 @@ type Coalescer struct {
 	// period during which coalesced writes can be
 	// buffered without flush to the underlying logger
 	deadband time.Duration
-	flushc chan chan error
+	flushc chan chan flushed
+	closec chan chan flushed
 	writec chan event.Record
-	timer *time.Timer
+	done   chan struct{}
+	trigger Trigger
+
+	// ceiling bounds how long a record can be held while
+	// writes keep extending the deadband; zero means no bound
+	ceiling  time.Duration
+	held     time.Time // when last was first buffered
+	watchdog *time.Timer
+
+	// budget bounds the encoded size of last; zero means no bound
+	budget int
+
+	statmu sync.Mutex
+	stats  CoalescerStats
+	types  map[string]*TypeStats
+}
+
+// CoalescerOption configures a Coalescer
+type CoalescerOption func(*Coalescer)
+
+// WithCeiling bounds how long the Coalescer holds a pending record. A steady
+// stream of coalescing writes keeps extending the deadband; once the record
+// has been pending for the ceiling (e.g. 10x the deadband) it is flushed
+// anyway and a warning is logged.
+func WithCeiling(ceiling time.Duration) CoalescerOption {
+	return func(c *Coalescer) {
+		c.ceiling = ceiling
+	}
+}
+
+// WithBudget bounds the memory held by the pending record. Once the
+// encoded size of the coalesced record exceeds budget bytes it is spilled
+// to the underlying logger immediately, and counted in the Spills stat,
+// rather than growing without bound during a burst. Checking the budget
+// encodes the pending record after every write.
+func WithBudget(budget int) CoalescerOption {
+	return func(c *Coalescer) {
+		c.budget = budget
+	}
+}
+
+// WithDeadbandFunc sets the deadband per record: once a record is pending,
+// the Coalescer waits f(record) for it to coalesce instead of the deadband
+// given to NewCoalescer. A zero duration flushes the record as soon as it is
+// written, e.g. for commands that should never be merged. It is shorthand for
+// WithTrigger(DeadbandFunc(f)).
+func WithDeadbandFunc(f func(event.Record) time.Duration) CoalescerOption {
+	return WithTrigger(DeadbandFunc(f))
+}
+
+// Marker is implemented by loggers that group records into units, such as
+// an undo stack. A Coalescer calls Mark on its underlying logger after its
+// deadband expires, or its Trigger fires, and the coalesced record is flushed.
+type Marker interface {
+	Mark()
 }
 
 // NewCoalescer wraps the given logger and returns a coalescer
-func NewCoalescer(lg Logger, deadband time.Duration) *Coalescer {
+func NewCoalescer(lg Logger, deadband time.Duration, opts ...CoalescerOption) *Coalescer {
 	c := &Coalescer{
 		Logger:   lg,
 		last:     nil,
 		deadband: deadband,
-		timer: time.NewTimer(deadband),
+		trigger:  Deadband(deadband),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 	c.run()
 	return c
 }
 
+// Deadband returns the coalescing period given to NewCoalescer
+func (l *Coalescer) Deadband() time.Duration {
+	return l.deadband
+}
+
 // ReadAt reads and returns log record n
 func (l *Coalescer) ReadAt(n int64) (event.Record, error) {
 	return l.Logger.ReadAt(n)
@@ -49,70 +120,225 @@ func (l *Coalescer) combine(v event.Record) bool{
 }
 
 func (l *Coalescer) run(){
-	l.flushc = make(chan chan error)
+	l.flushc = make(chan chan flushed)
+	l.closec = make(chan chan flushed)
 	l.writec = make(chan event.Record)
-	l.reclock()
+	l.done = make(chan struct{})
+	if l.ceiling > 0 {
+		l.watchdog = time.NewTimer(l.ceiling)
+	}
 	go func(){
 	for{
 		select{
-		case <- l.timer.C:
+		case <- l.trigger.Next():
 			// deadline expired, flush what we have now
-			l.flush()
-			l.reclock()
+			l.mark()
+		case <- l.watchc():
+			if l.stalled() {
+				log.Printf("worm: coalescer held a record for %v, forcing flush", time.Since(l.held))
+				l.mark()
+			}
 		case v := <- l.writec:
+			l.statmu.Lock()
+			l.stats.Writes++
+			l.typeStats(v).Writes++
+			l.statmu.Unlock()
 			if l.last == nil{
 				// keep going
-				l.last = v
-				continue
-			}
-			fused := l.combine(v)
-			if !fused{
+				l.hold(v)
+			} else if l.combine(v) {
+				l.statmu.Lock()
+				l.typeStats(v).Merges++
+				l.statmu.Unlock()
+			} else {
 				l.flush()
-				l.last = v
+				l.hold(v)
+			}
+			if l.trigger.OnWrite(l.last) {
+				l.mark()
+			} else if l.overBudget() {
+				l.statmu.Lock()
+				l.stats.Spills++
+				l.statmu.Unlock()
+				l.mark()
 			}
-			l.reclock() // deadline extended 
 		case donec := <- l.flushc:
 			// the user did this with a public function
-			l.flush()
-			l.reclock()
-			donec <- nil
+			ok := l.last != nil
+			err := l.mark()
+			if r, isReset := l.trigger.(resetter); isReset {
+				r.reset()
+			}
+			donec <- flushed{ok, err}
+		case donec := <- l.closec:
+			err := l.mark()
+			if r, isReset := l.trigger.(resetter); isReset {
+				r.reset()
+			}
+			if l.watchdog != nil {
+				l.watchdog.Stop()
+			}
+			close(l.done)
+			donec <- flushed{err: err}
 			return
 		}
 	}
 	}()
 }
 
-func (l *Coalescer) reclock(){
-			if !l.timer.Stop() {
-				<-l.timer.C
-			}
-			l.timer.Reset(l.deadband)
+// hold makes v the pending record and starts the watchdog on it
+func (l *Coalescer) hold(v event.Record) {
+	l.last = v
+	l.held = time.Now()
+	if l.watchdog == nil {
+		return
+	}
+	if !l.watchdog.Stop() {
+		select {
+		case <-l.watchdog.C:
+		default:
+		}
+	}
+	l.watchdog.Reset(l.ceiling)
+}
+
+// watchc returns the watchdog's channel, or nil if there is no ceiling
+func (l *Coalescer) watchc() <-chan time.Time {
+	if l.watchdog == nil {
+		return nil
+	}
+	return l.watchdog.C
+}
+
+// stalled reports whether the pending record has reached the ceiling
+func (l *Coalescer) stalled() bool {
+	return l.ceiling > 0 && l.last != nil && time.Since(l.held) >= l.ceiling
+}
+
+// overBudget reports whether the pending record has outgrown the budget
+func (l *Coalescer) overBudget() bool {
+	if l.budget <= 0 || l.last == nil {
+		return false
+	}
+	b, err := encode(l.last)
+	return err == nil && len(b) > l.budget
 }
 
 // Write writes v to the tail of the log
 func (l *Coalescer) Write(v event.Record) (err error) {
-	l.writec <- v
-	return nil 
+	select {
+	case l.writec <- v:
+		return nil
+	case <-l.done:
+		return ErrClosed
+	}
+}
+
+// Stats returns the Coalescer's statistics so far
+func (l *Coalescer) Stats() CoalescerStats {
+	l.statmu.Lock()
+	defer l.statmu.Unlock()
+	s := l.stats
+	s.Types = make(map[string]TypeStats, len(l.types))
+	for k, t := range l.types {
+		s.Types[k] = *t
+	}
+	return s
+}
+
+// typeStats returns the statistics for the type of v; statmu must be held
+func (l *Coalescer) typeStats(v event.Record) *TypeStats {
+	k := fmt.Sprintf("%T", inner(v))
+	t, ok := l.types[k]
+	if !ok {
+		if l.types == nil {
+			l.types = make(map[string]*TypeStats)
+		}
+		t = &TypeStats{}
+		l.types[k] = t
+	}
+	return t
+}
+
+// flushed is the result of a flush requested through flushc
+type flushed struct {
+	ok  bool
+	err error
 }
 
-// Flush flushes the last unwritten log to the underlying logger
+// Flush flushes the last unwritten log to the underlying logger. The
+// pending slot is cleared and the deadband restarts with the next write;
+// flushing with nothing pending does nothing.
 func (l *Coalescer) Flush() error{
-	donec := make(chan error)
-	l.flushc <- donec
-	return <- donec
+	_, err := l.TryFlush(context.Background())
+	return err
+}
+
+// TryFlush is Flush, but gives up waiting when ctx is done and reports
+// whether a record was pending. A flush already started when ctx is done
+// still completes in the background.
+func (l *Coalescer) TryFlush(ctx context.Context) (bool, error) {
+	donec := make(chan flushed, 1)
+	select {
+	case l.flushc <- donec:
+	case <-l.done:
+		return false, ErrClosed
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	select {
+	case r := <-donec:
+		return r.ok, r.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Close flushes the pending record and stops the Coalescer's goroutine and
+// timers. Later writes and flushes return ErrClosed; closing again does
+// nothing. The underlying logger is not closed.
+func (l *Coalescer) Close() error {
+	donec := make(chan flushed, 1)
+	select {
+	case l.closec <- donec:
+	case <-l.done:
+		return nil
+	}
+	return (<-donec).err
+}
+
+// mark flushes the pending record and tells a Marker underneath that
+// the current unit of work is complete
+func (l *Coalescer) mark() error {
+	if l.last == nil {
+		return nil
+	}
+	err := l.flush()
+	if m, ok := l.Logger.(Marker); ok {
+		m.Mark()
+	}
+	return err
 }
+
 func (l *Coalescer) flush() error {
 	if l.last == nil{
 		return nil
 	}
-	l.Logger.Write(l.last)
+	err := l.Logger.Write(l.last)
+	l.statmu.Lock()
+	l.stats.Flushed++
+	l.stats.Delay.Observe(time.Since(l.held))
+	l.typeStats(l.last).Flushed++
+	l.statmu.Unlock()
 	switch e := l.last.(type){
 	case *event.Write:
 		if e.Residue != nil{
 			l.last = e.Residue
-			l.flush()
+			if rerr := l.flush(); err == nil {
+				err = rerr
+			}
 		}
 	}
 	l.last = nil
-	return nil
+	return err
 }
@@ -0,0 +1,135 @@
+package worm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// ConsumerState is the committed position of a Consumer
+type ConsumerState struct {
+	Offset int64    // index of the next record to deliver
+	Keys   []string // idempotency keys of the latest records delivered
+}
+
+// OffsetStore saves consumer state across restarts. Load returns the zero
+// state for a consumer that never committed.
+type OffsetStore interface {
+	Load(name string) (ConsumerState, error)
+	Save(name string, s ConsumerState) error
+}
+
+// NewMemOffsets returns an OffsetStore kept in memory
+func NewMemOffsets() OffsetStore {
+	return &memOffsets{m: make(map[string]ConsumerState)}
+}
+
+type memOffsets struct {
+	mu sync.Mutex
+	m  map[string]ConsumerState
+}
+
+func (s *memOffsets) Load(name string) (ConsumerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.m[name]
+	c.Keys = append([]string(nil), c.Keys...)
+	return c, nil
+}
+
+func (s *memOffsets) Save(name string, c ConsumerState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Keys = append([]string(nil), c.Keys...)
+	s.m[name] = c
+	return nil
+}
+
+// Consumer delivers the records of a log to a function once each, across
+// restarts. Delivery is at least once, from the last committed offset, and
+// records whose idempotency key was already delivered are skipped, so a
+// record written twice by a retrying producer is seen once. A crash between
+// delivery and the next commit redelivers the records since the commit:
+// fn must tolerate repeats of those, e.g. by keying its own effects on the
+// same key, or Batch must be one.
+type Consumer struct {
+	Name  string
+	Store OffsetStore
+
+	// Key returns the idempotency key of a record; records with an empty
+	// key are never skipped. A nil Key skips nothing.
+	Key func(event.Record) string
+
+	// Batch is the number of records delivered between commits. The
+	// consumer also commits whenever it catches up with the log.
+	Batch int
+
+	// Window is the number of recent keys remembered
+	Window int
+}
+
+// NewConsumer returns a consumer named name committing to store, with a
+// Batch of 100 and a Window of 10000
+func NewConsumer(name string, store OffsetStore, key func(event.Record) string) *Consumer {
+	return &Consumer{Name: name, Store: store, Key: key, Batch: 100, Window: 10000}
+}
+
+// Offset returns the committed offset of the consumer called name, so a
+// Consumer's store can be watched by a Throttle
+func (c *Consumer) Offset(name string) (int64, error) {
+	s, err := c.Store.Load(name)
+	return s.Offset, err
+}
+
+// Run follows lg from the committed offset and calls fn with each record
+// not yet delivered, until ctx is done or fn fails. A failed record is
+// not committed and is delivered again by the next Run.
+func (c *Consumer) Run(ctx context.Context, lg Logger, fn func(n int64, v event.Record) error) error {
+	state, err := c.Store.Load(c.Name)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(state.Keys))
+	for _, k := range state.Keys {
+		seen[k] = true
+	}
+	pending := 0
+	commit := func() error {
+		if over := len(state.Keys) - c.Window; c.Window > 0 && over > 0 {
+			for _, k := range state.Keys[:over] {
+				delete(seen, k)
+			}
+			state.Keys = append(state.Keys[:0], state.Keys[over:]...)
+		}
+		pending = 0
+		return c.Store.Save(c.Name, state)
+	}
+	err = Follow(ctx, lg, state.Offset, func(n int64, v event.Record) error {
+		key := ""
+		if c.Key != nil {
+			key = c.Key(v)
+		}
+		if key == "" || !seen[key] {
+			if err := fn(n, v); err != nil {
+				return err
+			}
+			if key != "" {
+				seen[key] = true
+				state.Keys = append(state.Keys, key)
+			}
+		}
+		state.Offset = n + 1
+		if pending++; pending >= c.Batch || state.Offset >= lg.Len() {
+			return commit()
+		}
+		return nil
+	})
+	if pending > 0 {
+		if cerr := commit(); cerr != nil {
+			return errors.Join(err, cerr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package worm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// chunk is the number of records bulk operations such as Migration's
+// backfill, Chain.Join, Backup and VerifyDeep handle as one unit of work,
+// so locks are held briefly and work can be paused or shared out
+const chunk = 256
+
+// ErrChecksum is returned when a record does not read back with the same
+// contents it was written with, such as a copied record or a stored blob
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Copy appends records [from, to) of src to dst and returns the number of
+// records copied. Each record is read back from dst after it is written and
+// its checksum compared to the source, so dst must append synchronously; a
+// buffering logger such as a Coalescer should be flushed and read from
+// instead. Copy stops early if ctx is done.
+func Copy(ctx context.Context, dst, src Logger, from, to int64) (n int64, err error) {
+	if from < 0 || to < from || to > src.Len() {
+		return 0, fmt.Errorf("bad copy range: [%d, %d)", from, to)
+	}
+	for at := from; at < to; at++ {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		v, err := src.ReadAt(at)
+		if err != nil {
+			return n, err
+		}
+		want, err := checksum(v)
+		if err != nil {
+			return n, err
+		}
+		tail := dst.Len()
+		if err = dst.Write(v); err != nil {
+			return n, err
+		}
+		if dst.Len() != tail+1 {
+			return n, fmt.Errorf("copy record %d: destination did not append it", at)
+		}
+		w, err := dst.ReadAt(tail)
+		if err != nil {
+			return n, err
+		}
+		got, err := checksum(w)
+		if err != nil {
+			return n, err
+		}
+		if got != want {
+			return n, alert("copy", at, fmt.Errorf("copy record %d: %w", at, ErrChecksum))
+		}
+		n++
+	}
+	return n, nil
+}
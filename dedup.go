@@ -0,0 +1,89 @@
+package worm
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// Deduper is a logger that drops a write whose contents equal one of the
+// last few records written
+type Deduper struct {
+	Logger
+
+	mu         sync.Mutex
+	recent     [][sha256.Size]byte // ring of the last window checksums
+	next       int
+	seen       map[[sha256.Size]byte]int
+	suppressed int64
+}
+
+// Dedup wraps lg, suppressing writes that duplicate any of the last window
+// records. The window is seeded from the records already at the tail of lg.
+// A window of zero or less suppresses nothing.
+func Dedup(lg Logger, window int) *Deduper {
+	if window < 0 {
+		window = 0
+	}
+	d := &Deduper{
+		Logger: lg,
+		recent: make([][sha256.Size]byte, 0, window),
+		seen:   make(map[[sha256.Size]byte]int),
+	}
+	from := lg.Len() - int64(window)
+	if from < 0 {
+		from = 0
+	}
+	for n := from; n < lg.Len(); n++ {
+		if v, err := lg.ReadAt(n); err == nil {
+			if sum, err := checksum(v); err == nil {
+				d.remember(sum)
+			}
+		}
+	}
+	return d
+}
+
+// Write writes v to the tail of the log unless it duplicates a recent record
+func (d *Deduper) Write(v event.Record) error {
+	sum, err := checksum(v)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen[sum] > 0 {
+		d.suppressed++
+		return nil
+	}
+	if err = d.Logger.Write(v); err != nil {
+		return err
+	}
+	d.remember(sum)
+	return nil
+}
+
+// Suppressed returns the number of writes dropped as duplicates
+func (d *Deduper) Suppressed() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.suppressed
+}
+
+func (d *Deduper) remember(sum [sha256.Size]byte) {
+	if cap(d.recent) == 0 {
+		return
+	}
+	if len(d.recent) < cap(d.recent) {
+		d.recent = append(d.recent, sum)
+	} else {
+		old := d.recent[d.next]
+		if d.seen[old]--; d.seen[old] == 0 {
+			delete(d.seen, old)
+		}
+		d.recent[d.next] = sum
+		d.next = (d.next + 1) % len(d.recent)
+	}
+	d.seen[sum]++
+}
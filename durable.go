@@ -0,0 +1,45 @@
+package worm
+
+import (
+	"context"
+
+	"github.com/as/event"
+)
+
+type durableKey struct{}
+
+// WithDurable returns a copy of ctx whose writes by WriteContext are made
+// durable as if by WriteDurable
+func WithDurable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, durableKey{}, true)
+}
+
+// Durable reports whether ctx was returned by WithDurable
+func Durable(ctx context.Context) bool {
+	d, _ := ctx.Value(durableKey{}).(bool)
+	return d
+}
+
+// WriteDurable writes v to lg and does not return until it is durable: if
+// lg is a Flusher it is flushed and if it is a Syncer it is synced, whatever
+// buffering lg otherwise applies. Only lg itself is checked, so lg should be
+// the outermost logger that buffers.
+func WriteDurable(lg Logger, v event.Record) error {
+	if err := lg.Write(v); err != nil {
+		return err
+	}
+	return commit(lg)
+}
+
+// commit flushes and syncs lg, if it supports either
+func commit(lg Logger) error {
+	if f, ok := lg.(Flusher); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	if s, ok := lg.(Syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package worm
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/as/event"
+)
+
+// FollowPoll is how often Follow checks a caught-up log for new records
+const FollowPoll = 50 * time.Millisecond
+
+// Follow calls fn with each record of lg starting at index from, then keeps
+// waiting for and delivering new records as they are written. It returns
+// when ctx is done or fn or a read fails. A negative from counts back from
+// the tail. Lg is read while other goroutines write to it, so it must be
+// safe for concurrent use, as loggers from NewLogger are.
+func Follow(ctx context.Context, lg Logger, from int64, fn func(n int64, v event.Record) error) error {
+	from = fromTail(from, lg.Len())
+	if from < 0 {
+		from = 0
+	}
+	tick := time.NewTicker(FollowPoll)
+	defer tick.Stop()
+	for {
+		for end := lg.Len(); from < end; from++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			v, err := lg.ReadAt(from)
+			if err != nil {
+				return err
+			}
+			if err = fn(from, v); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// FollowFilter is Follow, but only delivers the records for which keep
+// returns true. Skipped records still advance the index passed to fn.
+func FollowFilter(ctx context.Context, lg Logger, from int64, keep func(event.Record) bool, fn func(n int64, v event.Record) error) error {
+	return Follow(ctx, lg, from, func(n int64, v event.Record) error {
+		if !keep(v) {
+			return nil
+		}
+		return fn(n, v)
+	})
+}
+
+// OfType returns a filter keeping records with the same concrete type as
+// one of protos, looking inside any envelopes
+func OfType(protos ...event.Record) func(event.Record) bool {
+	types := make(map[reflect.Type]bool, len(protos))
+	for _, p := range protos {
+		types[reflect.TypeOf(p)] = true
+	}
+	return func(v event.Record) bool {
+		return types[reflect.TypeOf(inner(v))]
+	}
+}
+
+// HasHeader returns a filter keeping records whose envelope carries header
+// k with value v
+func HasHeader(k, v string) func(event.Record) bool {
+	return func(r event.Record) bool {
+		_, h := Unwrap(r)
+		hv, ok := h[k]
+		return ok && hv == v
+	}
+}
@@ -0,0 +1,124 @@
+package worm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/as/event"
+)
+
+// FS returns a read-only file system exposing each record in lg as a file
+// named by its zero-padded index, e.g. 000000042. A file's contents are
+// the record's JSON encoding and its modification time is the record's
+// time, if it has one.
+func FS(lg Logger) fs.FS {
+	return logFS{lg}
+}
+
+type logFS struct {
+	lg Logger
+}
+
+// Open opens the named record, or "." for the directory of all records
+func (f logFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &logDir{fs: f}, nil
+	}
+	var n int64
+	if _, err := fmt.Sscanf(name, "%d", &n); err != nil || name != recordName(n) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info, b, err := f.stat(n)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &logFile{info: info, Reader: bytes.NewReader(b)}, nil
+}
+
+func (f logFS) stat(n int64) (*fileInfo, []byte, error) {
+	if n < 0 || n >= f.lg.Len() {
+		return nil, nil, fs.ErrNotExist
+	}
+	v, err := f.lg.ReadAt(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := encode(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &fileInfo{name: recordName(n), size: int64(len(b)), time: recordTime(v), rec: v}, b, nil
+}
+
+func recordName(n int64) string {
+	return fmt.Sprintf("%09d", n)
+}
+
+type logFile struct {
+	*bytes.Reader
+	info *fileInfo
+}
+
+func (f *logFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *logFile) Close() error               { return nil }
+
+type logDir struct {
+	fs logFS
+	at int64
+}
+
+func (d *logDir) Stat() (fs.FileInfo, error) { return &fileInfo{name: ".", dir: true}, nil }
+func (d *logDir) Close() error               { return nil }
+
+func (d *logDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: fs.ErrInvalid}
+}
+
+// ReadDir returns the next n records as directory entries
+func (d *logDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	end := d.fs.lg.Len()
+	if n > 0 && d.at+int64(n) < end {
+		end = d.at + int64(n)
+	}
+	if n > 0 && d.at >= end {
+		return nil, io.EOF
+	}
+	var list []fs.DirEntry
+	for ; d.at < end; d.at++ {
+		info, _, err := d.fs.stat(d.at)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, fs.FileInfoToDirEntry(info))
+	}
+	return list, nil
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	time time.Time
+	dir  bool
+	rec  event.Record
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) ModTime() time.Time { return i.time }
+func (i *fileInfo) IsDir() bool        { return i.dir }
+
+// Sys returns the record backing the file
+func (i *fileInfo) Sys() any { return i.rec }
+
+func (i *fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
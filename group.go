@@ -0,0 +1,76 @@
+package worm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Flusher is implemented by loggers that buffer records, such as a Coalescer
+type Flusher interface {
+	// Flush writes buffered records to the underlying logger
+	Flush() error
+}
+
+// Syncer is implemented by loggers backed by storage that must be synced
+// for writes to be durable
+type Syncer interface {
+	// Sync commits written records to stable storage
+	Sync() error
+}
+
+// Group collects the loggers of a program so they can be shut down together.
+// The zero value is an empty group ready to use.
+type Group struct {
+	mu      sync.Mutex
+	members []any
+}
+
+// Register adds v to the group. V should implement at least one of Flusher,
+// Syncer and io.Closer; anything else is ignored at shutdown.
+func (g *Group) Register(v any) {
+	g.mu.Lock()
+	g.members = append(g.members, v)
+	g.mu.Unlock()
+}
+
+// Shutdown flushes, then syncs, then closes every member of the group. Each
+// step runs in reverse order of registration, so wrappers registered after
+// the loggers they wrap are flushed into them first. Shutdown keeps going
+// after a failure and returns all errors joined. If ctx is done first,
+// Shutdown returns its error while the remaining steps carry on in the
+// background.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	members := g.members
+	g.members = nil
+	g.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		for i := len(members) - 1; i >= 0; i-- {
+			if f, ok := members[i].(Flusher); ok {
+				errs = append(errs, f.Flush())
+			}
+		}
+		for i := len(members) - 1; i >= 0; i-- {
+			if s, ok := members[i].(Syncer); ok {
+				errs = append(errs, s.Sync())
+			}
+		}
+		for i := len(members) - 1; i >= 0; i-- {
+			if c, ok := members[i].(io.Closer); ok {
+				errs = append(errs, c.Close())
+			}
+		}
+		done <- errors.Join(errs...)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+package worm
+
+import (
+	"context"
+
+	"github.com/as/event"
+)
+
+// Header holds user-defined metadata about a record, such as its origin,
+// trace ID, tenant or schema version
+type Header map[string]string
+
+// Envelope wraps a record with a Header. Middleware can route or audit on
+// the header without looking at the record.
+type Envelope struct {
+	Header Header
+	Record event.Record
+}
+
+// Coalesce coalesces the wrapped records of two envelopes with equal headers
+func (e *Envelope) Coalesce(v event.Record) event.Record {
+	w, ok := v.(*Envelope)
+	if !ok || !e.Header.equal(w.Header) {
+		return nil
+	}
+	next := e.Record.Coalesce(w.Record)
+	if next == nil {
+		return nil
+	}
+	return &Envelope{Header: e.Header, Record: next}
+}
+
+func (h Header) equal(g Header) bool {
+	if len(h) != len(g) {
+		return false
+	}
+	for k, v := range h {
+		if w, ok := g[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Unwrap returns the record inside an Envelope and its header. Any other
+// record is returned as is with a nil header.
+func Unwrap(v event.Record) (event.Record, Header) {
+	if e, ok := v.(*Envelope); ok {
+		return e.Record, e.Header
+	}
+	return v, nil
+}
+
+// bare returns the record inside any envelopes around v. Checks for types
+// that wrap records themselves, such as Sequenced, use it; checks for other
+// types use inner.
+func bare(v event.Record) event.Record {
+	for {
+		e, ok := v.(*Envelope)
+		if !ok {
+			return v
+		}
+		v = e.Record
+	}
+}
+
+type headerKey struct{}
+
+// WithHeader returns a copy of ctx carrying header k=v in addition to any
+// headers already in ctx
+func WithHeader(ctx context.Context, k, v string) context.Context {
+	old := HeaderFrom(ctx)
+	h := make(Header, len(old)+1)
+	for k, v := range old {
+		h[k] = v
+	}
+	h[k] = v
+	return context.WithValue(ctx, headerKey{}, h)
+}
+
+// HeaderFrom returns the headers carried by ctx. The result must not be
+// modified.
+func HeaderFrom(ctx context.Context) Header {
+	h, _ := ctx.Value(headerKey{}).(Header)
+	return h
+}
+
+// WriteContext writes v to lg in an Envelope holding the headers in ctx. If
+// ctx carries no headers v is written unwrapped. If ctx is Durable the write
+// is made durable as by WriteDurable.
+func WriteContext(ctx context.Context, lg Logger, v event.Record) error {
+	if err := WriteHeader(lg, v, HeaderFrom(ctx)); err != nil || !Durable(ctx) {
+		return err
+	}
+	return commit(lg)
+}
+
+// WriteHeader writes v to lg in an Envelope holding h. If h is empty v is
+// written unwrapped.
+func WriteHeader(lg Logger, v event.Record, h Header) error {
+	if len(h) == 0 {
+		return lg.Write(v)
+	}
+	return lg.Write(&Envelope{Header: h, Record: v})
+}
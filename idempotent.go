@@ -0,0 +1,104 @@
+package worm
+
+import (
+	"sync"
+
+	"github.com/as/event"
+)
+
+// Sequenced is a record tagged with its producer's ID and a sequence number
+// the producer increases with every new record. Retrying the write of the
+// same Sequenced record is safe when the log is wrapped by an Idempotent.
+type Sequenced struct {
+	Producer string
+	Seq      uint64
+	Record   event.Record
+}
+
+// Coalesce returns nil, sequenced records are never merged
+func (s *Sequenced) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Producer assigns sequence numbers to the records of one producer
+type Producer struct {
+	id  string
+	mu  sync.Mutex
+	seq uint64
+}
+
+// NewProducer returns a producer with the given ID. Seq is the last sequence
+// number the producer used, or zero for a new producer.
+func NewProducer(id string, seq uint64) *Producer {
+	return &Producer{id: id, seq: seq}
+}
+
+// Next returns v tagged with the producer's next sequence number. Retries
+// must write the returned record, not call Next again.
+func (p *Producer) Next(v event.Record) *Sequenced {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	return &Sequenced{Producer: p.id, Seq: p.seq, Record: v}
+}
+
+// Idempotent is a logger that ignores any Sequenced record whose sequence
+// number is not greater than the last one written for its producer. Other
+// records are written as is.
+type Idempotent struct {
+	Logger
+
+	mu      sync.Mutex
+	last    map[string]uint64
+	ignored int64
+}
+
+// NewIdempotent wraps lg, learning the last sequence number of every
+// producer from the records already in it
+func NewIdempotent(lg Logger) *Idempotent {
+	d := &Idempotent{Logger: lg, last: make(map[string]uint64)}
+	for n := int64(0); n < lg.Len(); n++ {
+		v, err := lg.ReadAt(n)
+		if err != nil {
+			continue
+		}
+		if s, ok := bare(v).(*Sequenced); ok && s.Seq > d.last[s.Producer] {
+			d.last[s.Producer] = s.Seq
+		}
+	}
+	return d
+}
+
+// Write writes v to the tail of the log unless it is a Sequenced record
+// that was already written
+func (d *Idempotent) Write(v event.Record) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := bare(v).(*Sequenced)
+	if !ok {
+		return d.Logger.Write(v)
+	}
+	if s.Seq <= d.last[s.Producer] {
+		d.ignored++
+		return nil
+	}
+	if err := d.Logger.Write(v); err != nil {
+		return err
+	}
+	d.last[s.Producer] = s.Seq
+	return nil
+}
+
+// Last returns the last sequence number written for producer
+func (d *Idempotent) Last(producer string) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.last[producer]
+}
+
+// Ignored returns the number of writes ignored as already seen
+func (d *Idempotent) Ignored() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ignored
+}
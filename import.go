@@ -0,0 +1,134 @@
+package worm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/as/event"
+)
+
+// ImportPoll is how often ImportTail checks a file for new lines
+const ImportPoll = 250 * time.Millisecond
+
+// A LineFunc converts one line of an existing log into a record. It returns
+// a nil record to skip the line.
+type LineFunc func(line []byte) (event.Record, error)
+
+// JSONLines returns a LineFunc decoding each line as a JSON object and
+// passing it to fn. Blank lines are skipped.
+func JSONLines(fn func(map[string]any) (event.Record, error)) LineFunc {
+	return func(line []byte) (event.Record, error) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil, nil
+		}
+		var m map[string]any
+		if err := json.Unmarshal(line, &m); err != nil {
+			return nil, err
+		}
+		return fn(m)
+	}
+}
+
+// Regexp returns a LineFunc passing the submatches of re in each line to
+// fn. Lines not matching re are skipped.
+func Regexp(re *regexp.Regexp, fn func(match []string) (event.Record, error)) LineFunc {
+	return func(line []byte) (event.Record, error) {
+		m := re.FindSubmatch(line)
+		if m == nil {
+			return nil, nil
+		}
+		s := make([]string, len(m))
+		for i := range m {
+			s[i] = string(m[i])
+		}
+		return fn(s)
+	}
+}
+
+// Import reads lines from r until EOF, converts each with fn and writes the
+// records to lg. It returns the number of records written. Errors name the
+// line they occurred on.
+func Import(ctx context.Context, lg Logger, r io.Reader, fn LineFunc) (n int64, err error) {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		if err = ctx.Err(); err != nil {
+			return n, err
+		}
+		b, rerr := br.ReadBytes('\n')
+		if len(b) > 0 {
+			ok, err := importLine(lg, fn, line, b)
+			if err != nil {
+				return n, err
+			}
+			if ok {
+				n++
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// ImportTail is Import on the file at path, except that on reaching the end
+// of the file it keeps waiting for and importing new lines until ctx is
+// done. A partial last line is held until its newline is written.
+func ImportTail(ctx context.Context, lg Logger, path string, fn LineFunc) (n int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	tick := time.NewTicker(ImportPoll)
+	defer tick.Stop()
+	var partial []byte
+	for line := 1; ; {
+		b, rerr := br.ReadBytes('\n')
+		partial = append(partial, b...)
+		if rerr == nil {
+			ok, err := importLine(lg, fn, line, partial)
+			if err != nil {
+				return n, err
+			}
+			if ok {
+				n++
+			}
+			line++
+			partial = nil // fn may keep the line it was given
+			continue
+		}
+		if !errors.Is(rerr, io.EOF) {
+			return n, rerr
+		}
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// importLine converts line number line and writes the record, reporting
+// whether the line produced one
+func importLine(lg Logger, fn LineFunc, line int, b []byte) (bool, error) {
+	v, err := fn(bytes.TrimRight(b, "\r\n"))
+	if err != nil {
+		return false, fmt.Errorf("import line %d: %w", line, err)
+	}
+	if v == nil {
+		return false, nil
+	}
+	return true, lg.Write(v)
+}
@@ -0,0 +1,153 @@
+package worm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// Data is a record holding an opaque byte payload. It never coalesces.
+type Data []byte
+
+// Coalesce returns nil, byte payloads are kept as written
+func (d Data) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// payload returns the bytes v contributes to a byte stream: the payload of
+// a Data record or the encoding of anything else
+func payload(v event.Record) ([]byte, error) {
+	if d, ok := v.(Data); ok {
+		return d, nil
+	}
+	return encode(v)
+}
+
+// NewWriter returns a writer that appends each call to Write to lg as one
+// Data record
+func NewWriter(lg Logger) io.Writer {
+	return &recordWriter{lg: lg}
+}
+
+type recordWriter struct {
+	lg Logger
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if err := w.lg.Write(Data(append([]byte(nil), p...))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// LineWriter is a writer that appends one Data record to its logger for
+// each line written to it. Records include the trailing newline.
+type LineWriter struct {
+	lg      Logger
+	partial []byte
+}
+
+// NewLineWriter returns a LineWriter appending to lg
+func NewLineWriter(lg Logger) *LineWriter {
+	return &LineWriter{lg: lg}
+}
+
+// Write appends every complete line in p and buffers the rest until the
+// next newline or Flush
+func (w *LineWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			return n + len(p), nil
+		}
+		line := append(w.partial, p[:i+1]...)
+		w.partial = nil
+		if err = w.lg.Write(Data(line)); err != nil {
+			return n, err
+		}
+		n += i + 1
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush appends the buffered partial line, if any
+func (w *LineWriter) Flush() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	line := w.partial
+	w.partial = nil
+	return w.lg.Write(Data(line))
+}
+
+// NewReaderAt returns a reader over the concatenated payloads of the
+// records in lg. Data records contribute their bytes and other records
+// their JSON encoding. Reads see records appended after the reader is
+// created.
+func NewReaderAt(lg Logger) io.ReaderAt {
+	return &recordReader{lg: lg, off: []int64{0}}
+}
+
+type recordReader struct {
+	lg Logger
+
+	mu sync.Mutex
+	// off[i] is the byte offset of record i; the last element is the
+	// offset just past the last record scanned
+	off []int64
+}
+
+func (r *recordReader) ReadAt(p []byte, at int64) (n int, err error) {
+	if at < 0 {
+		return 0, fmt.Errorf("bad read offset: %d", at)
+	}
+	for n < len(p) {
+		i, err := r.find(at + int64(n))
+		if err != nil {
+			return n, err
+		}
+		v, err := r.lg.ReadAt(i)
+		if err != nil {
+			return n, err
+		}
+		b, err := payload(v)
+		if err != nil {
+			return n, err
+		}
+		r.mu.Lock()
+		start := r.off[i]
+		r.mu.Unlock()
+		n += copy(p[n:], b[at+int64(n)-start:])
+	}
+	return n, nil
+}
+
+// find returns the index of the record containing byte offset at,
+// scanning records not yet seen as needed
+func (r *recordReader) find(at int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.off[len(r.off)-1] <= at {
+		i := int64(len(r.off) - 1)
+		if i >= r.lg.Len() {
+			return 0, io.EOF
+		}
+		v, err := r.lg.ReadAt(i)
+		if err != nil {
+			return 0, err
+		}
+		b, err := payload(v)
+		if err != nil {
+			return 0, err
+		}
+		r.off = append(r.off, r.off[i]+int64(len(b)))
+	}
+	i := sort.Search(len(r.off), func(i int) bool { return r.off[i] > at })
+	return int64(i - 1), nil
+}
@@ -8,15 +8,22 @@
 package worm
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"github.com/as/event"
 )
 
+// ErrReadOnly is returned by Write on loggers that only provide a view of
+// other logs
+var ErrReadOnly = errors.New("read-only log")
+
 type Logger interface{
 	// Write appends the record to the log
 	Write(event.Record) (err error)
 
-	// ReadAt reads and returns log record n
+	// ReadAt reads and returns log record n. A negative n counts back
+	// from the tail, so -1 is the most recent record.
 	ReadAt(at int64) (event.Record, error)
 
 	// Len returns the number of records
@@ -24,31 +31,67 @@ type Logger interface{
 
 }
 
+// Truncater is implemented by loggers that can discard their tail. It is
+// only used to repair a diverged copy of a log; Loggers are otherwise
+// append-only.
+type Truncater interface {
+	// Truncate discards record n and all records after it
+	Truncate(n int64) error
+}
+
 // NewLogger returns a Write-Once Read-Many (WORM) logger capable of
-// serializing an ordered stream of event.Records.
+// serializing an ordered stream of event.Records. It is safe for
+// concurrent use, so a Follow can read it while producers write.
 func NewLogger() Logger{
 	return &logWORM{}
 }
 
 type logWORM struct {
+	mu  sync.RWMutex
 	rec []event.Record
 }
 
 // ReadAt reads and returns log record n
 func (l *logWORM) ReadAt(n int64) (event.Record,  error){
-	if n < 0 || n >= int64(len(l.rec)){
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	at := fromTail(n, int64(len(l.rec)))
+	if at < 0 || at >= int64(len(l.rec)){
 		return nil, fmt.Errorf("bad read offset: %d\n", n)
 	}
-	return l.rec[n], nil
+	return l.rec[at], nil
 }
 
 // Write writes v to the tail of the log
 func (l *logWORM) Write(v event.Record) (err error){
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.rec = append(l.rec, v)
 	return nil
 }
 
 // Len returns the number of records stored the log
 func (l *logWORM) Len() int64{
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return int64(len(l.rec))
 }
+
+// Truncate discards record n and all records after it
+func (l *logWORM) Truncate(n int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n < 0 || n > int64(len(l.rec)) {
+		return fmt.Errorf("bad truncate offset: %d", n)
+	}
+	l.rec = l.rec[:n:n]
+	return nil
+}
+
+// fromTail resolves a negative record index against a log of length n
+func fromTail(at, n int64) int64 {
+	if at < 0 {
+		return at + n
+	}
+	return at
+}
@@ -0,0 +1,153 @@
+package worm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a maintenance task run periodically by a Scheduler, such as a
+// Compact into a fresh log or a VerifyDeep audit
+type Job struct {
+	Name   string
+	Every  time.Duration
+	Jitter time.Duration // up to this much is added to each wait
+	Run    func(ctx context.Context) error
+}
+
+// JobResult reports the last run of a job
+type JobResult struct {
+	Name     string
+	Runs     int64
+	Last     time.Time     // when the last run started
+	Duration time.Duration // how long it took
+	Err      error         // what it returned
+	Deferred int64         // times the job waited out write load
+}
+
+// Scheduler runs maintenance jobs over a log on their intervals
+type Scheduler struct {
+	lg Logger
+
+	// MaxRate pauses jobs that are due while the log is taking more than
+	// this many writes per second; zero never pauses
+	MaxRate float64
+
+	// Poll is how often a paused job rechecks the write rate, and the
+	// period the rate is measured over
+	Poll time.Duration
+
+	mu      sync.Mutex
+	jobs    []Job
+	results map[string]*JobResult
+}
+
+// NewScheduler returns a scheduler for jobs maintaining lg
+func NewScheduler(lg Logger) *Scheduler {
+	return &Scheduler{
+		lg:      lg,
+		Poll:    time.Second,
+		results: make(map[string]*JobResult),
+	}
+}
+
+// Add adds j to the scheduler. It must be called before Run.
+func (s *Scheduler) Add(j Job) error {
+	if j.Name == "" || j.Run == nil || j.Every <= 0 {
+		return errors.New("incomplete maintenance job")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.results[j.Name]; ok {
+		return fmt.Errorf("maintenance job %q already added", j.Name)
+	}
+	s.jobs = append(s.jobs, j)
+	s.results[j.Name] = &JobResult{Name: j.Name}
+	return nil
+}
+
+// Run runs every job on its interval until ctx is done. A failing job is
+// recorded in Results and runs again on its next interval.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j Job) {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	for {
+		wait := j.Every
+		if j.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(j.Jitter)))
+		}
+		if !sleep(ctx, wait) {
+			return
+		}
+		for s.busy(ctx) {
+			s.mu.Lock()
+			s.results[j.Name].Deferred++
+			s.mu.Unlock()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		start := time.Now()
+		err := j.Run(ctx)
+		s.mu.Lock()
+		r := s.results[j.Name]
+		r.Runs++
+		r.Last, r.Duration, r.Err = start, time.Since(start), err
+		s.mu.Unlock()
+	}
+}
+
+// busy measures the write rate over one Poll and reports whether it is
+// over MaxRate. It reports false once ctx is done.
+func (s *Scheduler) busy(ctx context.Context) bool {
+	if s.MaxRate <= 0 {
+		return false
+	}
+	n := s.lg.Len()
+	if !sleep(ctx, s.Poll) {
+		return false
+	}
+	return float64(s.lg.Len()-n)/s.Poll.Seconds() > s.MaxRate
+}
+
+// sleep waits for d and reports whether ctx is still live
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Results returns the last result of every job, ordered by name
+func (s *Scheduler) Results() []JobResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]JobResult, 0, len(s.results))
+	for _, r := range s.results {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
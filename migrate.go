@@ -0,0 +1,113 @@
+package worm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// Migration is a logger that moves a live log from src to dst. Until dst
+// has caught up, writes go to src and a background backfill copies src to
+// dst. Once caught up, writes go to both and reads are served from dst.
+// The producer never has to stop; it only waits while a chunk of the
+// backfill is copied.
+type Migration struct {
+	src, dst Logger
+
+	mu      sync.RWMutex
+	flipped bool
+	done    chan struct{}
+	err     error
+}
+
+// NewMigration starts migrating src to dst. Dst should be empty or hold a
+// verified prefix of src, such as one left by an earlier cancelled run.
+func NewMigration(ctx context.Context, src, dst Logger) *Migration {
+	m := &Migration{src: src, dst: dst, done: make(chan struct{})}
+	go m.backfill(ctx)
+	return m
+}
+
+func (m *Migration) backfill(ctx context.Context) {
+	defer close(m.done)
+	for {
+		m.mu.RLock()
+		from, to := m.dst.Len(), m.src.Len()
+		if to-from > chunk {
+			to = from + chunk
+		}
+		_, err := Copy(ctx, m.dst, m.src, from, to)
+		m.mu.RUnlock()
+		if err != nil {
+			m.mu.Lock()
+			m.err = err
+			m.mu.Unlock()
+			return
+		}
+		if from == to {
+			m.mu.Lock()
+			caught := m.dst.Len() == m.src.Len()
+			m.flipped = caught
+			m.mu.Unlock()
+			if caught {
+				return
+			}
+		}
+	}
+}
+
+// Wait blocks until the backfill finishes and returns its error, if any.
+// If the backfill fails, reads and writes stay on src. A later failure to
+// write to dst is reported the same way.
+func (m *Migration) Wait() error {
+	<-m.done
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.err
+}
+
+// Migrated reports whether reads have flipped to dst
+func (m *Migration) Migrated() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.flipped
+}
+
+// Write writes v to src and, once migrated, to dst. If the write to dst
+// fails, v is already in src, so dst can no longer keep step: reads go back
+// to src, dst is written no more, and the error is returned by Wait.
+func (m *Migration) Write(v event.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.src.Write(v); err != nil {
+		return err
+	}
+	if m.flipped {
+		if err := m.dst.Write(v); err != nil {
+			m.err = err
+			m.flipped = false
+		}
+	}
+	return nil
+}
+
+// ReadAt reads and returns log record n
+func (m *Migration) ReadAt(n int64) (event.Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.flipped {
+		return m.dst.ReadAt(n)
+	}
+	return m.src.ReadAt(n)
+}
+
+// Len returns the number of records
+func (m *Migration) Len() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.flipped {
+		return m.dst.Len()
+	}
+	return m.src.Len()
+}
@@ -0,0 +1,81 @@
+package worm
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// MultiReader is a read-only logger presenting several logs as one
+// timeline. Records are interleaved by time, then by the position of their
+// log in the list, then by index. Indices are stable: records appended to
+// the logs later are placed after every record already visible, in the same
+// order among themselves, so Follow and stored offsets keep working as the
+// logs grow.
+type MultiReader struct {
+	mu   sync.Mutex
+	m    refLog
+	seen []int64 // records of each log already placed
+}
+
+// NewMultiReader returns a MultiReader over lgs
+func NewMultiReader(lgs ...Logger) *MultiReader {
+	return &MultiReader{
+		m:    refLog{lgs: lgs},
+		seen: make([]int64, len(lgs)),
+	}
+}
+
+// refresh places records written to the logs since the last call
+func (r *MultiReader) refresh() {
+	type key struct {
+		ref
+		t int64
+	}
+	var batch []key
+	for i, lg := range r.m.lgs {
+		for n := lg.Len(); r.seen[i] < n; r.seen[i]++ {
+			k := key{ref: ref{i, r.seen[i]}}
+			if v, err := lg.ReadAt(r.seen[i]); err == nil {
+				if t := recordTime(v); !t.IsZero() {
+					k.t = t.UnixNano()
+				}
+			}
+			batch = append(batch, k)
+		}
+	}
+	sort.SliceStable(batch, func(i, j int) bool {
+		a, b := batch[i], batch[j]
+		if a.t != b.t {
+			return a.t < b.t
+		}
+		return a.lg < b.lg
+	})
+	for _, k := range batch {
+		r.m.refs = append(r.m.refs, k.ref)
+	}
+}
+
+// Write returns ErrReadOnly
+func (r *MultiReader) Write(event.Record) error {
+	return ErrReadOnly
+}
+
+// ReadAt reads and returns record n of the timeline
+func (r *MultiReader) ReadAt(n int64) (event.Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n < 0 || n >= int64(len(r.m.refs)) {
+		r.refresh()
+	}
+	return r.m.ReadAt(n)
+}
+
+// Len returns the number of records across all logs
+func (r *MultiReader) Len() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refresh()
+	return r.m.Len()
+}
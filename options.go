@@ -0,0 +1,61 @@
+package worm
+
+import (
+	"context"
+
+	"github.com/as/event"
+)
+
+// ReadOptions selects which soft-deleted records a reader sees. The zero
+// value hides them all.
+type ReadOptions struct {
+	// Superseded keeps records named by tombstones, and the tombstones
+	Superseded bool
+
+	// Redacted keeps the placeholders a Redactor returns for redacted
+	// records
+	Redacted bool
+}
+
+// keep reports whether v, on its own, should be shown
+func (o ReadOptions) keep(v event.Record) bool {
+	switch inner(v).(type) {
+	case *Tombstone:
+		return o.Superseded
+	case *Redacted:
+		return o.Redacted
+	}
+	return true
+}
+
+// Select returns a read-only view of lg showing only the records o allows,
+// so Len, ReadAt and anything built on them agree on what was deleted. Like
+// Live, the view is fixed when Select is called.
+func Select(lg Logger, o ReadOptions) (Logger, error) {
+	if !o.Superseded {
+		var err error
+		if lg, err = Live(lg); err != nil {
+			return nil, err
+		}
+	}
+	n := lg.Len()
+	view := &refLog{lgs: []Logger{lg}, refs: make([]ref, 0, n)}
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return nil, err
+		}
+		if o.keep(v) {
+			view.refs = append(view.refs, ref{0, at})
+		}
+	}
+	return view, nil
+}
+
+// FollowSelect is Follow, delivering only the records o allows.
+// Tombstones and redaction placeholders are skipped as they arrive, but a
+// record delivered before a later tombstone names it cannot be taken back.
+// Indices passed to fn are those of lg.
+func FollowSelect(ctx context.Context, lg Logger, from int64, o ReadOptions, fn func(n int64, v event.Record) error) error {
+	return FollowFilter(ctx, lg, from, o.keep, fn)
+}
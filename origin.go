@@ -0,0 +1,74 @@
+package worm
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/as/event"
+)
+
+// Header keys holding a record's Origin
+const (
+	OriginNode   = "origin.node"
+	OriginPID    = "origin.pid"
+	OriginWindow = "origin.window"
+	OriginUser   = "origin.user"
+)
+
+// Origin identifies where a record was written, for attributing the
+// records of logs with several sources. Empty fields are unknown.
+type Origin struct {
+	Node   string // host or cluster node
+	PID    int
+	Window string // editor or terminal window
+	User   string
+}
+
+// LocalOrigin returns the origin of the current process: its host name and
+// process ID
+func LocalOrigin() Origin {
+	host, _ := os.Hostname()
+	return Origin{Node: host, PID: os.Getpid()}
+}
+
+// WithOrigin returns a copy of ctx carrying the known fields of o as
+// headers, so records written with WriteContext carry them
+func WithOrigin(ctx context.Context, o Origin) context.Context {
+	for k, v := range o.header() {
+		ctx = WithHeader(ctx, k, v)
+	}
+	return ctx
+}
+
+// OriginFrom returns the origin carried by ctx
+func OriginFrom(ctx context.Context) Origin {
+	return originOf(HeaderFrom(ctx))
+}
+
+// OriginOf returns the origin in the envelope of v, if any
+func OriginOf(v event.Record) Origin {
+	_, h := Unwrap(v)
+	return originOf(h)
+}
+
+func (o Origin) header() Header {
+	h := make(Header)
+	set := func(k, v string) {
+		if v != "" {
+			h[k] = v
+		}
+	}
+	set(OriginNode, o.Node)
+	if o.PID != 0 {
+		set(OriginPID, strconv.Itoa(o.PID))
+	}
+	set(OriginWindow, o.Window)
+	set(OriginUser, o.User)
+	return h
+}
+
+func originOf(h Header) Origin {
+	pid, _ := strconv.Atoi(h[OriginPID])
+	return Origin{Node: h[OriginNode], PID: pid, Window: h[OriginWindow], User: h[OriginUser]}
+}
@@ -0,0 +1,171 @@
+package p9
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// 9P2000 message types
+const (
+	Tversion = 100 + iota
+	Rversion
+	Tauth
+	Rauth
+	Tattach
+	Rattach
+	Terror // illegal
+	Rerror
+	Tflush
+	Rflush
+	Twalk
+	Rwalk
+	Topen
+	Ropen
+	Tcreate
+	Rcreate
+	Tread
+	Rread
+	Twrite
+	Rwrite
+	Tclunk
+	Rclunk
+	Tremove
+	Rremove
+	Tstat
+	Rstat
+	Twstat
+	Rwstat
+)
+
+const (
+	qtDir = 0x80
+	dmDir = 0x80000000
+
+	oWrite = 1
+	oRdwr  = 2
+	oTrunc = 0x10
+
+	noTag = 0xffff
+	noFid = 0xffffffff
+
+	ioHdrSize = 24
+	maxMsize  = 64 << 10
+	maxWelem  = 16 // names in one Twalk
+)
+
+var errBadMsg = errors.New("malformed 9p message")
+
+type qid struct {
+	typ  uint8
+	vers uint32
+	path uint64
+}
+
+type dir struct {
+	qid    qid
+	mode   uint32
+	mtime  uint32
+	length uint64
+	name   string
+}
+
+// readMsg reads one size-prefixed message from r and returns it without
+// the size field
+func readMsg(r io.Reader, msize uint32) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < 7 || n > msize {
+		return nil, errBadMsg
+	}
+	b := make([]byte, n-4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// decoder consumes fields from a message. Once a read runs past the end of
+// the message all further reads return zero values and bad is set.
+type decoder struct {
+	b   []byte
+	bad bool
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.bad || len(d.b) < n {
+		// n comes from the message, only allocate for fixed-size fields
+		d.bad = true
+		if n > 8 {
+			return nil
+		}
+		return make([]byte, n)
+	}
+	p := d.b[:n]
+	d.b = d.b[n:]
+	return p
+}
+
+func (d *decoder) u8() uint8   { return d.next(1)[0] }
+func (d *decoder) u16() uint16 { return binary.LittleEndian.Uint16(d.next(2)) }
+func (d *decoder) u32() uint32 { return binary.LittleEndian.Uint32(d.next(4)) }
+func (d *decoder) u64() uint64 { return binary.LittleEndian.Uint64(d.next(8)) }
+func (d *decoder) str() string { return string(d.next(int(d.u16()))) }
+
+// encoder builds a message; the size field is filled in by bytes
+type encoder struct {
+	b []byte
+}
+
+func newEncoder(typ uint8, tag uint16) *encoder {
+	e := &encoder{b: make([]byte, 4, 64)}
+	e.u8(typ)
+	e.u16(tag)
+	return e
+}
+
+func (e *encoder) u8(v uint8)   { e.b = append(e.b, v) }
+func (e *encoder) u16(v uint16) { e.b = binary.LittleEndian.AppendUint16(e.b, v) }
+func (e *encoder) u32(v uint32) { e.b = binary.LittleEndian.AppendUint32(e.b, v) }
+func (e *encoder) u64(v uint64) { e.b = binary.LittleEndian.AppendUint64(e.b, v) }
+
+func (e *encoder) str(s string) {
+	e.u16(uint16(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *encoder) qid(q qid) {
+	e.u8(q.typ)
+	e.u32(q.vers)
+	e.u64(q.path)
+}
+
+// stat appends d in the machine-independent directory entry format
+func (e *encoder) stat(d dir) {
+	at := len(e.b)
+	e.u16(0)
+	e.u16(0) // type
+	e.u32(0) // dev
+	e.qid(d.qid)
+	e.u32(d.mode)
+	e.u32(d.mtime) // atime
+	e.u32(d.mtime)
+	e.u64(d.length)
+	e.str(d.name)
+	e.str("worm") // uid
+	e.str("worm") // gid
+	e.str("")     // muid
+	e.put16(at, uint16(len(e.b)-at-2))
+}
+
+// put16 and put32 overwrite a count reserved earlier in the message
+func (e *encoder) put16(at int, v uint16) { binary.LittleEndian.PutUint16(e.b[at:], v) }
+func (e *encoder) put32(at int, v uint32) { binary.LittleEndian.PutUint32(e.b[at:], v) }
+
+func (e *encoder) bytes() []byte {
+	e.put32(0, uint32(len(e.b)))
+	return e.b
+}
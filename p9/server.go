@@ -0,0 +1,522 @@
+// Package p9 serves a worm.Logger over the 9P2000 protocol so acme-style
+// clients and plan9port tools can browse and append to a log.
+//
+// The file tree is
+//
+//	/ctl        read: "len N\n"; write: "flush" flushes a buffering logger
+//	/tail       read: the most recent record; write: append one record
+//	/log/       one read-only file per record, named as in worm.FS
+//
+// Files hold the JSON encoding of their record, as in worm.FS, except
+// that worm.Data records are served as their raw bytes. Each write to tail
+// is decoded into exactly one record. Records written
+// through tail are enveloped with the worm.Origin of the connection: the
+// attaching user and the client's network address.
+package p9
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"strings"
+
+	"github.com/as/event"
+	"github.com/as/worm"
+)
+
+var (
+	errPerm     = errors.New("permission denied")
+	errNotFound = errors.New("file not found")
+	errNotDir   = errors.New("not a directory")
+	errIsDir    = errors.New("is a directory")
+	errFid      = errors.New("unknown fid")
+	errFidInUse = errors.New("fid in use")
+	errOpen     = errors.New("fid already open")
+	errNotOpen  = errors.New("fid not open")
+	errOffset   = errors.New("bad offset in directory read")
+	errCtl      = errors.New("bad ctl message")
+)
+
+// Server serves a log over 9P2000
+type Server struct {
+	lg     worm.Logger
+	fsys   fs.FS
+	decode func([]byte) (event.Record, error)
+}
+
+// NewServer returns a server for lg. Bytes written to the tail file are
+// converted into records with decode; if decode is nil the log is served
+// read-only. Serve handles each connection in its own goroutine, so lg
+// must be safe for concurrent use, as loggers from worm.NewLogger are.
+func NewServer(lg worm.Logger, decode func([]byte) (event.Record, error)) *Server {
+	return &Server{lg: lg, fsys: worm.FS(lg), decode: decode}
+}
+
+// Serve accepts connections on l and serves each one in its own goroutine
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(c)
+	}
+}
+
+// ServeConn serves 9P requests read from rw until the client hangs up
+func (s *Server) ServeConn(rw io.ReadWriteCloser) error {
+	defer rw.Close()
+	c := &conn{Server: s, msize: maxMsize, fids: make(map[uint32]*fid)}
+	if nc, ok := rw.(net.Conn); ok {
+		c.origin.Node = nc.RemoteAddr().String()
+	}
+	for {
+		b, err := readMsg(rw, c.msize)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = rw.Write(c.handle(b)); err != nil {
+			return err
+		}
+	}
+}
+
+const (
+	nRoot = iota
+	nCtl
+	nTail
+	nLog
+	nRec // must be last, records use paths nRec+n
+)
+
+type node struct {
+	kind int
+	n    int64
+}
+
+func (n node) isDir() bool {
+	return n.kind == nRoot || n.kind == nLog
+}
+
+func (n node) qid() qid {
+	q := qid{path: uint64(n.kind)}
+	if n.kind == nRec {
+		q.path += uint64(n.n)
+	}
+	if n.isDir() {
+		q.typ = qtDir
+	}
+	return q
+}
+
+type fid struct {
+	node
+	open   bool
+	write  bool
+	data   []byte // file contents at open
+	dirAt  int64  // next directory entry
+	dirOff uint64 // directory offset the next read must use
+}
+
+type conn struct {
+	*Server
+	msize  uint32
+	fids   map[uint32]*fid
+	origin worm.Origin
+}
+
+func (c *conn) handle(b []byte) []byte {
+	d := &decoder{b: b}
+	typ, tag := d.u8(), d.u16()
+	e, err := c.dispatch(typ, tag, d)
+	if err != nil {
+		e = newEncoder(Rerror, tag)
+		e.str(err.Error())
+	}
+	return e.bytes()
+}
+
+func (c *conn) dispatch(typ uint8, tag uint16, d *decoder) (*encoder, error) {
+	switch typ {
+	case Tversion:
+		msize, version := d.u32(), d.str()
+		if d.bad {
+			return nil, errBadMsg
+		}
+		if msize > maxMsize {
+			msize = maxMsize
+		}
+		if msize <= ioHdrSize {
+			return nil, errBadMsg
+		}
+		if strings.HasPrefix(version, "9P2000") {
+			version = "9P2000"
+		} else {
+			version = "unknown"
+		}
+		c.msize = msize
+		c.fids = make(map[uint32]*fid)
+		e := newEncoder(Rversion, tag)
+		e.u32(msize)
+		e.str(version)
+		return e, nil
+	case Tauth:
+		return nil, errors.New("authentication not required")
+	case Tattach:
+		fid, _, uname, _ := d.u32(), d.u32(), d.str(), d.str()
+		if d.bad {
+			return nil, errBadMsg
+		}
+		c.origin.User = uname
+		if err := c.newFid(fid, node{kind: nRoot}); err != nil {
+			return nil, err
+		}
+		e := newEncoder(Rattach, tag)
+		e.qid(node{kind: nRoot}.qid())
+		return e, nil
+	case Tflush:
+		// requests are answered in order, so there is never one to cancel
+		d.u16()
+		return newEncoder(Rflush, tag), nil
+	case Twalk:
+		return c.walk(tag, d)
+	case Topen:
+		return c.open(tag, d)
+	case Tcreate:
+		return nil, errPerm
+	case Tread:
+		return c.read(tag, d)
+	case Twrite:
+		return c.write(tag, d)
+	case Tclunk, Tremove:
+		f, err := c.fid(d.u32(), d)
+		if err != nil {
+			return nil, err
+		}
+		delete(c.fids, f)
+		if typ == Tremove {
+			return nil, errPerm
+		}
+		return newEncoder(Rclunk, tag), nil
+	case Tstat:
+		f, err := c.fid(d.u32(), d)
+		if err != nil {
+			return nil, err
+		}
+		st, err := c.stat(c.fids[f].node)
+		if err != nil {
+			return nil, err
+		}
+		e := newEncoder(Rstat, tag)
+		at := len(e.b)
+		e.u16(0)
+		e.stat(st)
+		e.put16(at, uint16(len(e.b)-at-2))
+		return e, nil
+	case Twstat:
+		return nil, errPerm
+	}
+	return nil, fmt.Errorf("bad message type: %d", typ)
+}
+
+func (c *conn) fid(n uint32, d *decoder) (uint32, error) {
+	if d.bad {
+		return 0, errBadMsg
+	}
+	if _, ok := c.fids[n]; !ok {
+		return 0, errFid
+	}
+	return n, nil
+}
+
+func (c *conn) newFid(n uint32, nd node) error {
+	if _, ok := c.fids[n]; ok || n == noFid {
+		return errFidInUse
+	}
+	c.fids[n] = &fid{node: nd}
+	return nil
+}
+
+func (c *conn) walk(tag uint16, d *decoder) (*encoder, error) {
+	f, newfid, nwname := d.u32(), d.u32(), d.u16()
+	if d.bad || nwname > maxWelem {
+		return nil, errBadMsg
+	}
+	names := make([]string, nwname)
+	for i := range names {
+		names[i] = d.str()
+	}
+	if _, err := c.fid(f, d); err != nil {
+		return nil, err
+	}
+	from := c.fids[f]
+	if from.open {
+		return nil, errOpen
+	}
+	if _, ok := c.fids[newfid]; ok && newfid != f {
+		return nil, errFidInUse
+	}
+	e := newEncoder(Rwalk, tag)
+	at := len(e.b)
+	e.u16(0)
+	nd, walked := from.node, 0
+	for _, name := range names {
+		if !nd.isDir() {
+			if walked == 0 {
+				return nil, errNotDir
+			}
+			break
+		}
+		next, ok := c.walk1(nd, name)
+		if !ok {
+			if walked == 0 {
+				return nil, errNotFound
+			}
+			break
+		}
+		e.qid(next.qid())
+		nd = next
+		walked++
+	}
+	e.put16(at, uint16(walked))
+	if walked == len(names) {
+		c.fids[newfid] = &fid{node: nd}
+	}
+	return e, nil
+}
+
+func (c *conn) walk1(from node, name string) (node, bool) {
+	if name == ".." {
+		return node{kind: nRoot}, true
+	}
+	if from.kind == nRoot {
+		switch name {
+		case "ctl":
+			return node{kind: nCtl}, true
+		case "tail":
+			return node{kind: nTail}, true
+		case "log":
+			return node{kind: nLog}, true
+		}
+		return node{}, false
+	}
+	var n int64
+	if _, err := fmt.Sscanf(name, "%d", &n); err != nil || name != recordName(n) || n < 0 || n >= c.lg.Len() {
+		return node{}, false
+	}
+	return node{kind: nRec, n: n}, true
+}
+
+func (c *conn) open(tag uint16, d *decoder) (*encoder, error) {
+	n, mode := d.u32(), d.u8()
+	if _, err := c.fid(n, d); err != nil {
+		return nil, err
+	}
+	f := c.fids[n]
+	if f.open {
+		return nil, errOpen
+	}
+	write := mode&3 == oWrite || mode&3 == oRdwr || mode&oTrunc != 0
+	if write {
+		switch {
+		case f.isDir():
+			return nil, errIsDir
+		case f.kind == nRec, f.kind == nTail && c.decode == nil:
+			return nil, errPerm
+		}
+	}
+	if !f.isDir() {
+		data, err := c.contents(f.node)
+		if err != nil {
+			return nil, err
+		}
+		f.data = data
+	}
+	f.open, f.write = true, write
+	e := newEncoder(Ropen, tag)
+	e.qid(f.qid())
+	e.u32(c.msize - ioHdrSize)
+	return e, nil
+}
+
+func (c *conn) read(tag uint16, d *decoder) (*encoder, error) {
+	n, off, count := d.u32(), d.u64(), d.u32()
+	if _, err := c.fid(n, d); err != nil {
+		return nil, err
+	}
+	f := c.fids[n]
+	if !f.open {
+		return nil, errNotOpen
+	}
+	if max := c.msize - ioHdrSize; count > max {
+		count = max
+	}
+	e := newEncoder(Rread, tag)
+	at := len(e.b)
+	e.u32(0)
+	if f.isDir() {
+		if off == 0 {
+			f.dirAt, f.dirOff = 0, 0
+		}
+		if off != f.dirOff {
+			return nil, errOffset
+		}
+		for {
+			child, ok := c.child(f.node, f.dirAt)
+			if !ok {
+				break
+			}
+			st, err := c.stat(child)
+			if err != nil {
+				return nil, err
+			}
+			mark := len(e.b)
+			e.stat(st)
+			if len(e.b)-at-4 > int(count) {
+				e.b = e.b[:mark]
+				break
+			}
+			f.dirAt++
+		}
+		f.dirOff += uint64(len(e.b) - at - 4)
+	} else if off < uint64(len(f.data)) {
+		data := f.data[off:]
+		if len(data) > int(count) {
+			data = data[:count]
+		}
+		e.b = append(e.b, data...)
+	}
+	e.put32(at, uint32(len(e.b)-at-4))
+	return e, nil
+}
+
+func (c *conn) write(tag uint16, d *decoder) (*encoder, error) {
+	n, _, count := d.u32(), d.u64(), d.u32()
+	data := d.next(int(count))
+	if _, err := c.fid(n, d); err != nil {
+		return nil, err
+	}
+	f := c.fids[n]
+	if !f.open || !f.write {
+		return nil, errNotOpen
+	}
+	switch f.kind {
+	case nCtl:
+		if err := c.ctl(strings.TrimSpace(string(data))); err != nil {
+			return nil, err
+		}
+	case nTail:
+		v, err := c.decode(data)
+		if err != nil {
+			return nil, err
+		}
+		ctx := worm.WithOrigin(context.Background(), c.origin)
+		if err = worm.WriteContext(ctx, c.lg, v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errPerm
+	}
+	e := newEncoder(Rwrite, tag)
+	e.u32(count)
+	return e, nil
+}
+
+func (s *Server) ctl(msg string) error {
+	switch msg {
+	case "flush":
+		if fl, ok := s.lg.(interface{ Flush() error }); ok {
+			return fl.Flush()
+		}
+		return nil
+	}
+	return errCtl
+}
+
+// child returns the i'th entry of directory n
+func (s *Server) child(n node, i int64) (node, bool) {
+	if n.kind == nRoot {
+		kind := []int{nCtl, nLog, nTail}
+		if i >= int64(len(kind)) {
+			return node{}, false
+		}
+		return node{kind: kind[i]}, true
+	}
+	if i >= s.lg.Len() {
+		return node{}, false
+	}
+	return node{kind: nRec, n: i}, true
+}
+
+func (s *Server) contents(n node) ([]byte, error) {
+	switch n.kind {
+	case nCtl:
+		return []byte(fmt.Sprintf("len %d\n", s.lg.Len())), nil
+	case nTail:
+		if n.n = s.lg.Len() - 1; n.n < 0 {
+			return nil, nil
+		}
+	}
+	v, err := s.lg.ReadAt(n.n)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := unwrap(v).(worm.Data); ok {
+		// raw bytes, so clients read back what they wrote to tail
+		return d, nil
+	}
+	return fs.ReadFile(s.fsys, recordName(n.n))
+}
+
+// unwrap returns the record inside any envelopes around v
+func unwrap(v event.Record) event.Record {
+	for {
+		w, h := worm.Unwrap(v)
+		if h == nil {
+			return w
+		}
+		v = w
+	}
+}
+
+func (s *Server) stat(n node) (dir, error) {
+	d := dir{qid: n.qid(), mode: 0444}
+	switch n.kind {
+	case nRoot:
+		d.name, d.mode = "/", dmDir|0555
+		return d, nil
+	case nLog:
+		d.name, d.mode = "log", dmDir|0555
+		return d, nil
+	case nCtl:
+		d.name, d.mode = "ctl", 0644
+	case nTail:
+		d.name = "tail"
+		if s.decode != nil {
+			d.mode = 0644
+		}
+	case nRec:
+		info, err := fs.Stat(s.fsys, recordName(n.n))
+		if err != nil {
+			return d, err
+		}
+		d.name = info.Name()
+		if t := info.ModTime(); !t.IsZero() {
+			d.mtime = uint32(t.Unix())
+		}
+	}
+	data, err := s.contents(n)
+	d.length = uint64(len(data))
+	return d, err
+}
+
+// recordName matches the file names used by worm.FS
+func recordName(n int64) string {
+	return fmt.Sprintf("%09d", n)
+}
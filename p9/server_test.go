@@ -0,0 +1,63 @@
+package p9
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/as/event"
+	"github.com/as/worm"
+)
+
+// msg builds a request the way a client would send it, without the size
+// field readMsg strips
+func msg(typ uint8, fn func(e *encoder)) []byte {
+	e := newEncoder(typ, 1)
+	fn(e)
+	return e.bytes()[4:]
+}
+
+func FuzzHandle(f *testing.F) {
+	walk := func(names ...string) []byte {
+		return msg(Twalk, func(e *encoder) {
+			e.u32(0)
+			e.u32(1)
+			e.u16(uint16(len(names)))
+			for _, s := range names {
+				e.str(s)
+			}
+		})
+	}
+	seeds := [][]byte{
+		msg(Tversion, func(e *encoder) { e.u32(maxMsize); e.str("9P2000") }),
+		msg(Tattach, func(e *encoder) { e.u32(1); e.u32(noFid); e.str("u"); e.str("") }),
+		walk("log", recordName(0)),
+		walk("tail"),
+		walk("log", "-00000001"),
+		walk(".."),
+		msg(Topen, func(e *encoder) { e.u32(0); e.u8(0) }),
+		msg(Tread, func(e *encoder) { e.u32(0); e.u64(0); e.u32(maxMsize) }),
+		msg(Twrite, func(e *encoder) { e.u32(0); e.u64(0); e.u32(2); e.b = append(e.b, "hi"...) }),
+		msg(Tstat, func(e *encoder) { e.u32(0) }),
+		msg(Tclunk, func(e *encoder) { e.u32(0) }),
+		msg(Tflush, func(e *encoder) { e.u16(1) }),
+	}
+	for _, b := range seeds {
+		f.Add(b)
+		for n := 1; n < len(b); n += 3 {
+			f.Add(b[:n])
+		}
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		lg := worm.NewLogger()
+		lg.Write(worm.Data("record"))
+		s := NewServer(lg, func(b []byte) (event.Record, error) {
+			return worm.Data(b), nil
+		})
+		c := &conn{Server: s, msize: maxMsize, fids: make(map[uint32]*fid)}
+		c.handle(msg(Tattach, func(e *encoder) { e.u32(0); e.u32(noFid); e.str("u"); e.str("") }))
+		r := c.handle(b)
+		if len(r) < 7 || binary.LittleEndian.Uint32(r) != uint32(len(r)) {
+			t.Fatalf("malformed reply %x", r)
+		}
+	})
+}
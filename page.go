@@ -0,0 +1,44 @@
+package worm
+
+import (
+	"fmt"
+
+	"github.com/as/event"
+)
+
+// MaxPageBytes bounds the encoded size of the records returned by one call
+// to Read
+const MaxPageBytes = 4 << 20
+
+// Read returns up to limit records starting at from, stopping early once
+// the records returned reach MaxPageBytes, and the index to pass as from to
+// read the next page. A page holds at least one record if any remain, even
+// if it alone exceeds MaxPageBytes. A limit of zero or less means no limit
+// on the number of records. A negative from counts back from the tail, and
+// next equals lg.Len() once the log is exhausted.
+func Read(lg Logger, from int64, limit int) (recs []event.Record, next int64, err error) {
+	end := lg.Len()
+	from = fromTail(from, end)
+	if from < 0 {
+		return nil, 0, fmt.Errorf("bad read offset: %d", from-end)
+	}
+	if limit > 0 && from+int64(limit) < end {
+		end = from + int64(limit)
+	}
+	size := 0
+	for next = from; next < end; next++ {
+		v, err := lg.ReadAt(next)
+		if err != nil {
+			return recs, next, err
+		}
+		b, err := encode(v)
+		if err != nil {
+			return recs, next, err
+		}
+		if size += len(b); size > MaxPageBytes && len(recs) > 0 {
+			break
+		}
+		recs = append(recs, v)
+	}
+	return recs, next, nil
+}
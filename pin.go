@@ -0,0 +1,62 @@
+package worm
+
+import "sync"
+
+// Pins is a set of labelled record ranges protected from compaction until
+// released, e.g. the records of an incident under investigation. The zero
+// value is an empty set ready to use. A nil *Pins pins nothing: Pinned,
+// Labels and Unpin treat it as an empty set, but Pin needs a non-nil *Pins.
+type Pins struct {
+	mu sync.Mutex
+	m  map[string][2]int64
+}
+
+// Pin protects records [from, to) under label, replacing any range already
+// pinned under that label. P must not be nil.
+func (p *Pins) Pin(from, to int64, label string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.m == nil {
+		p.m = make(map[string][2]int64)
+	}
+	p.m[label] = [2]int64{from, to}
+}
+
+// Unpin releases the range pinned under label
+func (p *Pins) Unpin(label string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	delete(p.m, label)
+	p.mu.Unlock()
+}
+
+// Pinned reports whether record n is in any pinned range
+func (p *Pins) Pinned(n int64) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.m {
+		if r[0] <= n && n < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Labels returns the labels of the pinned ranges
+func (p *Pins) Labels() []string {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]string, 0, len(p.m))
+	for l := range p.m {
+		list = append(list, l)
+	}
+	return list
+}
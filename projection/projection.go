@@ -0,0 +1,262 @@
+// Package projection builds read models from a worm log.
+//
+// A Projection folds records into a model and can snapshot and restore it.
+// A Manager runs registered projections concurrently, each in its own
+// goroutine, saving checkpoints of offset and snapshot to a Store so a
+// restarted program resumes where it left off instead of replaying the
+// whole log. Any projection can be rebuilt from the start while the others
+// keep running.
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+	"github.com/as/worm"
+)
+
+// Projection is a named read model built by applying log records in order
+type Projection struct {
+	Name string
+
+	// Apply folds record n into the model
+	Apply func(n int64, v event.Record) error
+
+	// Snapshot encodes the model and Restore replaces the model with
+	// a snapshot. Restore(nil) resets the model to empty.
+	Snapshot func() ([]byte, error)
+	Restore  func([]byte) error
+}
+
+// Checkpoint is the saved state of a projection: the index of the next
+// record to apply and the snapshot of the model up to it
+type Checkpoint struct {
+	Offset   int64
+	Snapshot []byte
+}
+
+// Store persists checkpoints
+type Store interface {
+	// Load returns the last checkpoint saved for name; ok is false if
+	// there is none
+	Load(name string) (c Checkpoint, ok bool, err error)
+	Save(name string, c Checkpoint) error
+}
+
+// NewMemStore returns a Store holding checkpoints in memory
+func NewMemStore() Store {
+	return &memStore{m: make(map[string]Checkpoint)}
+}
+
+type memStore struct {
+	mu sync.Mutex
+	m  map[string]Checkpoint
+}
+
+func (s *memStore) Load(name string) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.m[name]
+	return c, ok, nil
+}
+
+func (s *memStore) Save(name string, c Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[name] = c
+	return nil
+}
+
+// Manager runs projections over one log
+type Manager struct {
+	lg    worm.Logger
+	store Store
+
+	// Every is the number of records applied between checkpoints. A
+	// checkpoint is also saved whenever a projection catches up.
+	Every int64
+
+	// Poll is how often a caught-up projection checks for new records
+	Poll time.Duration
+
+	mu   sync.Mutex
+	proj map[string]*runner
+}
+
+// NewManager returns a manager for projections of lg checkpointing to store
+func NewManager(lg worm.Logger, store Store) *Manager {
+	return &Manager{
+		lg:    lg,
+		store: store,
+		Every: 1000,
+		Poll:  100 * time.Millisecond,
+		proj:  make(map[string]*runner),
+	}
+}
+
+// Register adds p to the manager. It must be called before Run.
+func (m *Manager) Register(p Projection) error {
+	if p.Name == "" || p.Apply == nil || p.Snapshot == nil || p.Restore == nil {
+		return errors.New("projection: incomplete projection")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.proj[p.Name]; ok {
+		return fmt.Errorf("projection: %q already registered", p.Name)
+	}
+	m.proj[p.Name] = &runner{Projection: p, m: m}
+	return nil
+}
+
+// Run restores every projection from its last checkpoint and keeps them up
+// to date with the log until ctx is done. A projection whose Apply,
+// Snapshot or Restore fails stops; Run returns their errors joined once ctx
+// is done.
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	var wg sync.WaitGroup
+	errs := make([]error, 0, len(m.proj))
+	var errmu sync.Mutex
+	for _, r := range m.proj {
+		wg.Add(1)
+		go func(r *runner) {
+			defer wg.Done()
+			if err := r.run(ctx); err != nil && !errors.Is(err, ctx.Err()) {
+				errmu.Lock()
+				errs = append(errs, fmt.Errorf("projection %q: %w", r.Name, err))
+				errmu.Unlock()
+			}
+		}(r)
+	}
+	m.mu.Unlock()
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Rebuild resets the named projection and replays the log into it from the
+// start. The other projections keep running.
+func (m *Manager) Rebuild(name string) error {
+	r, err := m.runner(name)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err = r.Restore(nil); err != nil {
+		return err
+	}
+	r.offset, r.saved = 0, -1
+	return nil
+}
+
+// Offset returns the index of the next record the named projection will
+// apply
+func (m *Manager) Offset(name string) (int64, error) {
+	r, err := m.runner(name)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.offset, nil
+}
+
+func (m *Manager) runner(name string) (*runner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.proj[name]
+	if !ok {
+		return nil, fmt.Errorf("projection: %q not registered", name)
+	}
+	return r, nil
+}
+
+type runner struct {
+	Projection
+	m *Manager
+
+	mu     sync.Mutex // held while applying, so Rebuild waits for a batch
+	offset int64
+	saved  int64 // offset of the last checkpoint
+}
+
+func (r *runner) run(ctx context.Context) error {
+	c, ok, err := r.m.store.Load(r.Name)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err = r.Restore(c.Snapshot); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.offset, r.saved = c.Offset, c.Offset
+	r.mu.Unlock()
+
+	tick := time.NewTicker(r.m.Poll)
+	defer tick.Stop()
+	for {
+		if err := r.catchUp(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// catchUp applies records until the projection reaches the end of the log,
+// checkpointing every r.m.Every records and at the end
+func (r *runner) catchUp(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		end := r.m.lg.Len()
+		if r.m.Every > 0 && end-r.offset > r.m.Every {
+			end = r.offset + r.m.Every
+		}
+		err := r.apply(end)
+		if err == nil && r.offset != r.saved {
+			err = r.checkpoint()
+		}
+		caught := r.offset >= r.m.lg.Len()
+		r.mu.Unlock()
+		if err != nil || caught {
+			return err
+		}
+	}
+}
+
+func (r *runner) apply(end int64) error {
+	for ; r.offset < end; r.offset++ {
+		v, err := r.m.lg.ReadAt(r.offset)
+		if err != nil {
+			return err
+		}
+		if err = r.Apply(r.offset, v); err != nil {
+			return fmt.Errorf("apply record %d: %w", r.offset, err)
+		}
+	}
+	return nil
+}
+
+func (r *runner) checkpoint() error {
+	snap, err := r.Snapshot()
+	if err != nil {
+		return err
+	}
+	if err = r.m.store.Save(r.Name, Checkpoint{Offset: r.offset, Snapshot: snap}); err != nil {
+		return err
+	}
+	r.saved = r.offset
+	return nil
+}
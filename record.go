@@ -0,0 +1,41 @@
+package worm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"time"
+
+	"github.com/as/event"
+)
+
+// Timed is implemented by records that carry the time they were created.
+// Adapters that report or filter by time use it when it is available.
+type Timed interface {
+	Time() time.Time
+}
+
+// recordTime returns the time carried by v or the record inside its
+// envelopes, or the zero time
+func recordTime(v event.Record) time.Time {
+	if t, ok := v.(Timed); ok {
+		return t.Time()
+	}
+	if t, ok := inner(v).(Timed); ok {
+		return t.Time()
+	}
+	return time.Time{}
+}
+
+// encode returns the serialized form of v
+func encode(v event.Record) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// checksum returns the SHA-256 digest of v's encoding
+func checksum(v event.Record) ([sha256.Size]byte, error) {
+	b, err := encode(v)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
@@ -0,0 +1,99 @@
+package worm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+)
+
+// Redaction is an overlay record hiding record N of another log from
+// readers
+type Redaction struct {
+	N      int64
+	Reason string
+	At     time.Time
+}
+
+// Coalesce returns nil, redactions are never merged
+func (r *Redaction) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Time returns when the redaction was made
+func (r *Redaction) Time() time.Time {
+	return r.At
+}
+
+// Redacted is the placeholder returned by a Redactor in place of a
+// redacted record
+type Redacted struct {
+	N      int64
+	Reason string
+}
+
+// Coalesce returns nil, placeholders are never merged
+func (r *Redacted) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Redactor is a logger whose reads hide records redacted in an overlay log.
+// The redacted records remain in the underlying log, which privileged
+// readers, and VerifyAnchors, can still read directly.
+type Redactor struct {
+	Logger
+
+	overlay Logger
+	mu      sync.Mutex
+	seen    int64 // overlay records loaded into reason
+	reason  map[int64]string
+}
+
+// NewRedactor returns a Redactor reading lg through the redactions stored
+// in overlay. The overlay should be a log of its own, kept alongside lg.
+func NewRedactor(lg, overlay Logger) *Redactor {
+	return &Redactor{Logger: lg, overlay: overlay, reason: make(map[int64]string)}
+}
+
+// Redact hides record n from readers, recording why in the overlay
+func (r *Redactor) Redact(n int64, reason string) error {
+	if n < 0 || n >= r.Logger.Len() {
+		return fmt.Errorf("bad redact offset: %d", n)
+	}
+	return r.overlay.Write(&Redaction{N: n, Reason: reason, At: time.Now()})
+}
+
+// Redacted reports whether record n is redacted, and why
+func (r *Redactor) Redacted(n int64) (reason string, ok bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for end := r.overlay.Len(); r.seen < end; r.seen++ {
+		v, err := r.overlay.ReadAt(r.seen)
+		if err != nil {
+			return "", false, err
+		}
+		if x, ok := inner(v).(*Redaction); ok {
+			if _, dup := r.reason[x.N]; !dup {
+				r.reason[x.N] = x.Reason
+			}
+		}
+	}
+	reason, ok = r.reason[n]
+	return reason, ok, nil
+}
+
+// ReadAt returns the record at index n, or a *Redacted placeholder if it was
+// redacted. A negative n counts back from the tail.
+func (r *Redactor) ReadAt(n int64) (event.Record, error) {
+	if at := fromTail(n, r.Logger.Len()); at >= 0 {
+		reason, ok, err := r.Redacted(at)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &Redacted{N: at, Reason: reason}, nil
+		}
+	}
+	return r.Logger.ReadAt(n)
+}
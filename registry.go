@@ -0,0 +1,112 @@
+package worm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// ErrUnknownType is returned when decoding a record whose type tag is not
+// registered
+var ErrUnknownType = errors.New("unknown record type")
+
+// Registry maps type tags to concrete record types, so encoded records can
+// be decoded back into the type they were written as
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	tags  map[reflect.Type]string
+}
+
+// NewRegistry returns a registry holding the record types of this package
+func NewRegistry() *Registry {
+	r := &Registry{types: make(map[string]reflect.Type), tags: make(map[reflect.Type]string)}
+	for tag, v := range map[string]event.Record{
+		"worm.Data":         Data(nil),
+		"worm.Tombstone":    &Tombstone{},
+		"worm.Anchor":       &Anchor{},
+		"worm.SessionStart": &SessionStart{},
+		"worm.SessionEnd":   &SessionEnd{},
+		"worm.BlobRef":      &BlobRef{},
+		"worm.Redaction":    &Redaction{},
+		"worm.Redacted":     &Redacted{},
+		"worm.Commit":       &Commit{},
+		"worm.Abort":        &Abort{},
+		"worm.Alert":        &Alert{},
+	} {
+		r.Register(tag, v)
+	}
+	return r
+}
+
+// Register associates tag with the concrete type of v. Registering a tag
+// again with a different type is an error. Types holding other records in
+// event.Record fields, such as Envelope, must decode those fields
+// themselves.
+func (r *Registry) Register(tag string, v event.Record) error {
+	t := reflect.TypeOf(v)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.types[tag]; ok && old != t {
+		return fmt.Errorf("register %s: tag used by %v", tag, old)
+	}
+	r.types[tag] = t
+	r.tags[t] = tag
+	return nil
+}
+
+// tagged is the encoding of a record by a Registry
+type tagged struct {
+	Type   string
+	Record json.RawMessage
+}
+
+// Marshal encodes v with its type tag
+func (r *Registry) Marshal(v event.Record) ([]byte, error) {
+	r.mu.RLock()
+	tag, ok := r.tags[reflect.TypeOf(v)]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("marshal %T: %w", v, ErrUnknownType)
+	}
+	b, err := encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tagged{tag, b})
+}
+
+// Unmarshal decodes a record encoded by Marshal into its registered type
+func (r *Registry) Unmarshal(b []byte) (event.Record, error) {
+	var t tagged
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+	return r.Decode(t.Type, t.Record)
+}
+
+// Decode decodes the JSON encoding of a record registered under tag
+func (r *Registry) Decode(tag string, b []byte) (event.Record, error) {
+	r.mu.RLock()
+	t, ok := r.types[tag]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("decode %s: %w", tag, ErrUnknownType)
+	}
+	ptr := t.Kind() == reflect.Pointer
+	if ptr {
+		t = t.Elem()
+	}
+	p := reflect.New(t)
+	if err := json.Unmarshal(b, p.Interface()); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", tag, err)
+	}
+	if ptr {
+		return p.Interface().(event.Record), nil
+	}
+	return p.Elem().Interface().(event.Record), nil
+}
@@ -0,0 +1,68 @@
+package worm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Resync repairs a follower that has diverged from its primary. It finds the
+// first record whose checksum differs between the two, moves the follower's
+// records from that point on into quarantine, truncates the follower there
+// and re-streams the primary's records. A nil quarantine discards the bad
+// suffix. The follower must implement Truncater unless it is merely behind.
+// Resync returns the number of records streamed from the primary.
+func Resync(ctx context.Context, follower, primary, quarantine Logger) (n int64, err error) {
+	at, err := divergence(ctx, follower, primary)
+	if err != nil {
+		return 0, err
+	}
+	if end := follower.Len(); at < end {
+		t, ok := follower.(Truncater)
+		if !ok {
+			return 0, fmt.Errorf("follower diverges at record %d and cannot be truncated", at)
+		}
+		if quarantine != nil {
+			if _, err = Copy(ctx, quarantine, follower, at, end); err != nil {
+				return 0, err
+			}
+		}
+		if err = t.Truncate(at); err != nil {
+			return 0, err
+		}
+	}
+	return Copy(ctx, follower, primary, at, primary.Len())
+}
+
+// divergence returns the index of the first record that differs between a
+// and b, or the length of the shorter log if one is a prefix of the other
+func divergence(ctx context.Context, a, b Logger) (int64, error) {
+	end := a.Len()
+	if n := b.Len(); n < end {
+		end = n
+	}
+	for at := int64(0); at < end; at++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		va, err := a.ReadAt(at)
+		if err != nil {
+			return at, nil
+		}
+		vb, err := b.ReadAt(at)
+		if err != nil {
+			return 0, err
+		}
+		sa, err := checksum(va)
+		if err != nil {
+			return at, nil
+		}
+		sb, err := checksum(vb)
+		if err != nil {
+			return 0, err
+		}
+		if sa != sb {
+			return at, nil
+		}
+	}
+	return end, nil
+}
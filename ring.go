@@ -0,0 +1,89 @@
+package worm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// ErrEvicted is returned when reading a record a Ring no longer holds
+var ErrEvicted = errors.New("record evicted")
+
+// Ring is an in-memory logger keeping only its most recent records, for
+// debug views of recent events where durability is not needed. Records keep
+// the index they were written at; Len counts every record ever written and
+// Oldest is the index of the first one still held.
+type Ring struct {
+	mu  sync.RWMutex
+	rec []event.Record
+	n   int64 // records written
+}
+
+// RingLogger returns a Ring holding the last capacity records
+func RingLogger(capacity int) *Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring{rec: make([]event.Record, capacity)}
+}
+
+// Write writes v to the tail of the log, evicting the oldest record if the
+// ring is full
+func (r *Ring) Write(v event.Record) error {
+	r.mu.Lock()
+	r.rec[r.n%int64(len(r.rec))] = v
+	r.n++
+	r.mu.Unlock()
+	return nil
+}
+
+// ReadAt reads and returns log record n. A negative n counts back from the
+// tail. Reading a record that was evicted returns an error wrapping
+// ErrEvicted.
+func (r *Ring) ReadAt(n int64) (event.Record, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	at := fromTail(n, r.n)
+	if at < 0 || at >= r.n {
+		return nil, fmt.Errorf("bad read offset: %d", n)
+	}
+	if at < r.oldest() {
+		return nil, fmt.Errorf("read offset %d: %w", n, ErrEvicted)
+	}
+	return r.rec[at%int64(len(r.rec))], nil
+}
+
+// Len returns the number of records written
+func (r *Ring) Len() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.n
+}
+
+// Oldest returns the index of the oldest record held
+func (r *Ring) Oldest() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.oldest()
+}
+
+func (r *Ring) oldest() int64 {
+	if c := int64(len(r.rec)); r.n > c {
+		return r.n - c
+	}
+	return 0
+}
+
+// Records returns the records held, oldest first, and the index of the
+// first
+func (r *Ring) Records() (recs []event.Record, from int64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	from = r.oldest()
+	for at := from; at < r.n; at++ {
+		recs = append(recs, r.rec[at%int64(len(r.rec))])
+	}
+	return recs, from
+}
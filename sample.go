@@ -0,0 +1,65 @@
+package worm
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/as/event"
+)
+
+// Sampler is a logger that keeps a fraction of the records written to it
+type Sampler struct {
+	Logger
+
+	mu      sync.Mutex
+	keep    func(event.Record) bool // called under mu
+	force   func(event.Record) bool
+	dropped int64
+}
+
+// Sample wraps lg, keeping every keepOneIn'th record written and dropping
+// the rest. Records for which force returns true are always kept and do not
+// count towards the sample. A keepOneIn of one or less keeps everything and
+// a nil force forces nothing.
+func Sample(lg Logger, keepOneIn int, force func(event.Record) bool) *Sampler {
+	var n int
+	return &Sampler{Logger: lg, force: force, keep: func(event.Record) bool {
+		n++
+		if n >= keepOneIn {
+			n = 0
+			return true
+		}
+		return false
+	}}
+}
+
+// SampleRate is Sample, but keeps each record with probability p instead
+// of at a fixed interval
+func SampleRate(lg Logger, p float64, force func(event.Record) bool) *Sampler {
+	return &Sampler{Logger: lg, force: force, keep: func(event.Record) bool {
+		return rand.Float64() < p
+	}}
+}
+
+// Write writes v to the tail of the log if it is sampled or forced
+func (s *Sampler) Write(v event.Record) error {
+	if s.force == nil || !s.force(v) {
+		s.mu.Lock()
+		keep := s.keep(v)
+		if !keep {
+			s.dropped++
+		}
+		s.mu.Unlock()
+		if !keep {
+			return nil
+		}
+	}
+	return s.Logger.Write(v)
+}
+
+// Dropped returns the number of writes not sampled
+func (s *Sampler) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,124 @@
+package worm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/as/event"
+)
+
+// ErrNoSession is returned when a log has no session to replay
+var ErrNoSession = errors.New("no session")
+
+// SessionStart marks the start of session ID
+type SessionStart struct {
+	ID string
+	At time.Time
+}
+
+// Coalesce returns nil, session markers are never merged
+func (s *SessionStart) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Time returns when the session started
+func (s *SessionStart) Time() time.Time {
+	return s.At
+}
+
+// SessionEnd marks the end of session ID
+type SessionEnd struct {
+	ID string
+	At time.Time
+}
+
+// Coalesce returns nil, session markers are never merged
+func (s *SessionEnd) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Time returns when the session ended
+func (s *SessionEnd) Time() time.Time {
+	return s.At
+}
+
+// StartSession writes a marker starting session id to lg
+func StartSession(lg Logger, id string) error {
+	return lg.Write(&SessionStart{ID: id, At: time.Now()})
+}
+
+// EndSession writes a marker ending session id to lg
+func EndSession(lg Logger, id string) error {
+	return lg.Write(&SessionEnd{ID: id, At: time.Now()})
+}
+
+// Session is the range of records between a session's markers. The range
+// holds any record written while the session was open, including those of
+// other sessions open at the same time.
+type Session struct {
+	ID         string
+	From, To   int64 // records [From, To), not counting the markers
+	Start, End time.Time
+	Open       bool // no end marker; To is the log length when listed
+}
+
+// Sessions returns the sessions in lg in the order they started
+func Sessions(lg Logger) ([]Session, error) {
+	var list []Session
+	open := make(map[string]int)
+	n := lg.Len()
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return list, err
+		}
+		switch m := inner(v).(type) {
+		case *SessionStart:
+			open[m.ID] = len(list)
+			list = append(list, Session{ID: m.ID, From: at + 1, Start: m.At, Open: true})
+		case *SessionEnd:
+			i, ok := open[m.ID]
+			if !ok {
+				continue
+			}
+			delete(open, m.ID)
+			list[i].To, list[i].End, list[i].Open = at, m.At, false
+		}
+	}
+	for _, i := range open {
+		list[i].To = n
+	}
+	return list, nil
+}
+
+// LastSession returns the most recently started session in lg, or
+// ErrNoSession
+func LastSession(lg Logger) (Session, error) {
+	list, err := Sessions(lg)
+	if err != nil {
+		return Session{}, err
+	}
+	if len(list) == 0 {
+		return Session{}, ErrNoSession
+	}
+	return list[len(list)-1], nil
+}
+
+// Replay calls fn with each record of session s in lg, skipping the
+// markers of any other session
+func Replay(lg Logger, s Session, fn func(n int64, v event.Record) error) error {
+	for at := s.From; at < s.To; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return err
+		}
+		switch inner(v).(type) {
+		case *SessionStart, *SessionEnd:
+			continue
+		}
+		if err = fn(at, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
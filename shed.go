@@ -0,0 +1,86 @@
+package worm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+)
+
+// ShedProbe is how often a Shedder that is diverting writes lets one
+// through to the primary logger anyway, to learn whether it has recovered
+const ShedProbe = time.Second
+
+// Shedder is a logger that diverts writes to a fallback logger, such as an
+// asynchronous queue or a dead-letter log, when the primary logger is not
+// expected to finish them within the caller's deadline
+type Shedder struct {
+	Logger
+
+	fallback Logger
+
+	mu    sync.Mutex
+	ewma  time.Duration // smoothed latency of the primary logger
+	probe time.Time     // when a write last went to the primary
+	shed  int64
+}
+
+// NewShedder returns a Shedder writing to lg, or to fallback when lg is too
+// slow
+func NewShedder(lg, fallback Logger) *Shedder {
+	return &Shedder{Logger: lg, fallback: fallback}
+}
+
+// Write writes v to the primary logger, however long it takes
+func (s *Shedder) Write(v event.Record) error {
+	return s.primary(v)
+}
+
+// WriteContext writes v to the primary logger unless ctx has a deadline
+// the primary is not expected to meet, in which case v is written to the
+// fallback logger instead. The expected latency is a moving average of
+// recent writes; while writes are being diverted, one every ShedProbe goes
+// to the primary regardless to refresh it.
+func (s *Shedder) WriteContext(ctx context.Context, v event.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		s.mu.Lock()
+		divert := s.ewma > time.Until(dl) && time.Since(s.probe) < ShedProbe
+		if divert {
+			s.shed++
+		}
+		s.mu.Unlock()
+		if divert {
+			return s.fallback.Write(v)
+		}
+	}
+	return s.primary(v)
+}
+
+func (s *Shedder) primary(v event.Record) error {
+	start := time.Now()
+	err := s.Logger.Write(v)
+	d := time.Since(start)
+	s.mu.Lock()
+	s.ewma += (d - s.ewma) / 8
+	s.probe = start
+	s.mu.Unlock()
+	return err
+}
+
+// Shed returns the number of writes diverted to the fallback logger
+func (s *Shedder) Shed() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shed
+}
+
+// Latency returns the moving average of the primary logger's write latency
+func (s *Shedder) Latency() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ewma
+}
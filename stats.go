@@ -0,0 +1,97 @@
+package worm
+
+import (
+	"math"
+	"time"
+)
+
+// histBuckets is the number of buckets in a Histogram
+const histBuckets = 18
+
+// Histogram counts durations in exponentially growing buckets: bucket i
+// holds durations below 1ms<<i and the last bucket everything longer.
+type Histogram struct {
+	Counts [histBuckets]int64
+	Count  int64
+	Sum    time.Duration
+	Max    time.Duration
+}
+
+// Bound returns the exclusive upper bound of bucket i
+func (h *Histogram) Bound(i int) time.Duration {
+	if i >= histBuckets-1 {
+		return 1<<63 - 1
+	}
+	return time.Millisecond << i
+}
+
+// Observe adds d to the histogram
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < histBuckets-1 && d >= h.Bound(i) {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+}
+
+// Mean returns the average duration observed
+func (h *Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an upper bound for the q'th quantile, 0 <= q <= 1: the
+// bound of the bucket holding it, or Max if that is smaller
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.Count)))
+	seen := int64(0)
+	for i, n := range h.Counts {
+		if seen += n; seen >= rank && seen > 0 {
+			if b := h.Bound(i); b < h.Max {
+				return b
+			}
+			return h.Max
+		}
+	}
+	return 0
+}
+
+// CoalescerStats reports the work done by a Coalescer
+type CoalescerStats struct {
+	Writes  int64 // records written to the Coalescer
+	Flushed int64 // records passed to the underlying logger
+	Spills  int64 // records flushed early for exceeding the memory budget
+
+	// Delay is the time from a record being first buffered to it being
+	// flushed, the latency coalescing adds to each emitted record
+	Delay Histogram
+
+	// Types breaks the counts down by record type, inside any envelope
+	Types map[string]TypeStats
+}
+
+// TypeStats reports the work done by a Coalescer on one record type
+type TypeStats struct {
+	Writes  int64 // records of the type written to the Coalescer
+	Merges  int64 // writes coalesced into the pending record
+	Flushed int64 // pending records of the type flushed
+}
+
+// RunLength returns the average number of writes merged into each record
+// flushed: one if the type never coalesces, zero if none were flushed
+func (t TypeStats) RunLength() float64 {
+	if t.Flushed == 0 {
+		return 0
+	}
+	return float64(t.Writes) / float64(t.Flushed)
+}
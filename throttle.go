@@ -0,0 +1,112 @@
+package worm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/as/event"
+)
+
+// ThrottlePoll is how often a throttled write rechecks consumer lag
+const ThrottlePoll = 10 * time.Millisecond
+
+// Offsets reports the index of the next record a named consumer will read,
+// such as a projection.Manager
+type Offsets interface {
+	Offset(name string) (int64, error)
+}
+
+// Throttle is a logger that holds back writes while a watched consumer
+// lags too far behind the tail
+type Throttle struct {
+	Logger
+
+	mu    sync.Mutex
+	watch map[string]watch
+}
+
+type watch struct {
+	off    Offsets
+	maxLag int64
+}
+
+// NewThrottle returns a Throttle writing to lg. It throttles nothing until
+// consumers are watched.
+func NewThrottle(lg Logger) *Throttle {
+	return &Throttle{Logger: lg, watch: make(map[string]watch)}
+}
+
+// Watch throttles writes while consumer name, as reported by off, is more
+// than maxLag records behind the tail. Watching a name again replaces its
+// limit and a maxLag of zero or less stops watching it.
+func (t *Throttle) Watch(name string, off Offsets, maxLag int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if maxLag <= 0 {
+		delete(t.watch, name)
+		return
+	}
+	t.watch[name] = watch{off, maxLag}
+}
+
+// Lag returns how many records each watched consumer is behind the tail
+func (t *Throttle) Lag() (map[string]int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := t.Logger.Len()
+	lag := make(map[string]int64, len(t.watch))
+	for name, w := range t.watch {
+		n, err := w.off.Offset(name)
+		if err != nil {
+			return lag, err
+		}
+		lag[name] = end - n
+	}
+	return lag, nil
+}
+
+// Write writes v to the tail of the log once every watched consumer is
+// within its limit
+func (t *Throttle) Write(v event.Record) error {
+	return t.WriteContext(context.Background(), v)
+}
+
+// WriteContext is Write, but gives up waiting when ctx is done
+func (t *Throttle) WriteContext(ctx context.Context, v event.Record) error {
+	var tick *time.Ticker
+	for {
+		behind, err := t.behind()
+		if err != nil {
+			return err
+		}
+		if !behind {
+			return t.Logger.Write(v)
+		}
+		if tick == nil {
+			tick = time.NewTicker(ThrottlePoll)
+			defer tick.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// behind reports whether a watched consumer is over its limit
+func (t *Throttle) behind() (bool, error) {
+	lag, err := t.Lag()
+	if err != nil {
+		return false, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, n := range lag {
+		if w, ok := t.watch[name]; ok && n > w.maxLag {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,68 @@
+package worm
+
+import (
+	"context"
+
+	"github.com/as/event"
+)
+
+// Tombstone is a record declaring record N obsolete, e.g. because a later
+// record supersedes it. The log is never rewritten: readers that honor
+// tombstones use Live, and Compact drops dead records when copying a log.
+type Tombstone struct {
+	N      int64
+	Reason string
+}
+
+// Coalesce returns nil, tombstones are never merged
+func (t *Tombstone) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Live returns a read-only view of lg without the records named by
+// tombstones or the tombstones themselves. Tombstones naming other
+// tombstones have no effect. Like Merge, the view is fixed when Live is
+// called.
+func Live(lg Logger) (Logger, error) {
+	return live(lg, nil)
+}
+
+// live is Live, except that dead records in a pinned range are kept
+func live(lg Logger, pins *Pins) (Logger, error) {
+	n := lg.Len()
+	dead := make(map[int64]bool)
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := inner(v).(*Tombstone); ok {
+			dead[at] = true
+			if t.N >= 0 && t.N < at && !pins.Pinned(t.N) {
+				if w, err := lg.ReadAt(t.N); err == nil {
+					if _, ok := inner(w).(*Tombstone); !ok {
+						dead[t.N] = true
+					}
+				}
+			}
+		}
+	}
+	view := &refLog{lgs: []Logger{lg}, refs: make([]ref, 0, n-int64(len(dead)))}
+	for at := int64(0); at < n; at++ {
+		if !dead[at] {
+			view.refs = append(view.refs, ref{0, at})
+		}
+	}
+	return view, nil
+}
+
+// Compact copies the live records of src, as seen by Live, to dst and
+// returns the number copied. Records in a range pinned in pins are copied
+// even if a tombstone names them; pins may be nil. Src is left as is.
+func Compact(ctx context.Context, dst, src Logger, pins *Pins) (int64, error) {
+	view, err := live(src, pins)
+	if err != nil {
+		return 0, err
+	}
+	return Copy(ctx, dst, view, 0, view.Len())
+}
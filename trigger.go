@@ -0,0 +1,117 @@
+package worm
+
+import (
+	"time"
+
+	"github.com/as/event"
+)
+
+// Trigger decides when a Coalescer flushes its pending record. A Trigger
+// belongs to one Coalescer and is only called from its goroutine.
+type Trigger interface {
+	// OnWrite is called with the pending record after every write,
+	// whether the write coalesced into it or replaced it. Returning true
+	// flushes the record immediately.
+	OnWrite(pending event.Record) bool
+
+	// Next returns a channel that delivers when the pending record
+	// should be flushed. It is called again after every write and flush;
+	// a nil channel never delivers.
+	Next() <-chan time.Time
+}
+
+// resetter is implemented by triggers with a timer to stop when the
+// Coalescer is flushed explicitly
+type resetter interface {
+	reset()
+}
+
+// WithTrigger replaces the Coalescer's deadband with t
+func WithTrigger(t Trigger) CoalescerOption {
+	return func(c *Coalescer) {
+		c.trigger = t
+	}
+}
+
+// Deadband returns a trigger that flushes once no write has arrived for d
+func Deadband(d time.Duration) Trigger {
+	return DeadbandFunc(func(event.Record) time.Duration { return d })
+}
+
+// DeadbandFunc returns a trigger choosing the deadband for each pending
+// record. A zero duration flushes the record as soon as it is written.
+func DeadbandFunc(f func(event.Record) time.Duration) Trigger {
+	return &deadband{f: f}
+}
+
+type deadband struct {
+	f     func(event.Record) time.Duration
+	timer *time.Timer
+}
+
+func (d *deadband) OnWrite(v event.Record) bool {
+	wait := d.f(v)
+	if d.timer == nil {
+		d.timer = time.NewTimer(wait)
+	} else {
+		if !d.timer.Stop() {
+			select {
+			case <-d.timer.C:
+			default:
+			}
+		}
+		d.timer.Reset(wait)
+	}
+	return wait <= 0
+}
+
+// reset stops the timer after an explicit flush; the next write restarts it
+func (d *deadband) reset() {
+	if d.timer != nil && !d.timer.Stop() {
+		select {
+		case <-d.timer.C:
+		default:
+		}
+	}
+}
+
+func (d *deadband) Next() <-chan time.Time {
+	if d.timer == nil {
+		return nil
+	}
+	return d.timer.C
+}
+
+// FlushOn returns a trigger that flushes when marker reports true for the
+// pending record, e.g. a save command or a record grown past a size, and
+// otherwise defers to t
+func FlushOn(marker func(event.Record) bool, t Trigger) Trigger {
+	return &flushOn{Trigger: t, marker: marker}
+}
+
+type flushOn struct {
+	Trigger
+	marker func(event.Record) bool
+}
+
+func (f *flushOn) OnWrite(v event.Record) bool {
+	flush := f.Trigger.OnWrite(v)
+	return f.marker(v) || flush
+}
+
+func (f *flushOn) reset() {
+	if r, ok := f.Trigger.(resetter); ok {
+		r.reset()
+	}
+}
+
+// Signal returns a trigger that flushes only when c delivers, such as a
+// channel fed when an editor window loses focus
+func Signal(c <-chan time.Time) Trigger {
+	return signal(c)
+}
+
+type signal <-chan time.Time
+
+func (s signal) OnWrite(event.Record) bool { return false }
+func (s signal) Next() <-chan time.Time    { return s }
@@ -0,0 +1,54 @@
+package worm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/as/event"
+)
+
+// Recent returns a read-only view of the records of lg written within ttl
+// of now, e.g. the last day of activity, without deleting older ones. The
+// log is searched back from the tail until a record carrying a time older
+// than the cutoff, so records are assumed to be appended roughly in time
+// order; records without a time are kept if they follow the cutoff. Like
+// View, the view is frozen at the current length of lg.
+func Recent(lg Logger, ttl time.Duration) (Logger, error) {
+	cut := time.Now().Add(-ttl)
+	end := lg.Len()
+	from := end
+	for ; from > 0; from-- {
+		v, err := lg.ReadAt(from - 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := recordTime(v); !t.IsZero() && t.Before(cut) {
+			break
+		}
+	}
+	return &window{Logger: lg, from: from, n: end - from}, nil
+}
+
+// window is a read-only view of n records of a log starting at from
+type window struct {
+	Logger
+	from, n int64
+}
+
+func (w *window) Write(event.Record) error {
+	return ErrReadOnly
+}
+
+// ReadAt reads and returns record n of the view
+func (w *window) ReadAt(n int64) (event.Record, error) {
+	at := fromTail(n, w.n)
+	if at < 0 || at >= w.n {
+		return nil, fmt.Errorf("bad read offset: %d", n)
+	}
+	return w.Logger.ReadAt(w.from + at)
+}
+
+// Len returns the number of records in the view
+func (w *window) Len() int64 {
+	return w.n
+}
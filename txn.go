@@ -0,0 +1,200 @@
+package worm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/as/event"
+)
+
+// ErrInDoubt is wrapped by the error AppendAll returns when a transaction
+// committed on some logs but its commit marker could not be written to
+// others. Committed cannot show the record in those logs until Resolve
+// writes the missing Commit.
+var ErrInDoubt = errors.New("transaction in doubt")
+
+// Intent is the first phase of a transaction: it holds the record of
+// transaction Tx for one log, hidden by Committed until a Commit follows it
+type Intent struct {
+	Tx     string
+	Record event.Record
+}
+
+// Coalesce returns nil, transactional records are never merged
+func (i *Intent) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Commit makes the intents of transaction Tx visible
+type Commit struct {
+	Tx string
+}
+
+// Coalesce returns nil, commit markers are never merged
+func (c *Commit) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// Abort discards the intents of transaction Tx
+type Abort struct {
+	Tx string
+}
+
+// Coalesce returns nil, abort markers are never merged
+func (a *Abort) Coalesce(event.Record) event.Record {
+	return nil
+}
+
+// AppendAll appends each record to its log as one transaction: readers
+// using Committed see either every record or none of them. Each record is
+// first written as an Intent and, once every intent is written, a Commit is
+// written to every log. If an intent cannot be written the transaction is
+// aborted in the logs that hold one. A transaction interrupted between the
+// phases, by a crash or a failed marker write, is settled by Resolve.
+func AppendAll(recs map[Logger]event.Record) error {
+	tx, err := txid()
+	if err != nil {
+		return err
+	}
+	var done []Logger
+	for lg, v := range recs {
+		if err = lg.Write(&Intent{Tx: tx, Record: v}); err != nil {
+			break
+		}
+		done = append(done, lg)
+	}
+	if err != nil {
+		errs := []error{fmt.Errorf("transaction %s: %w", tx, err)}
+		for _, lg := range done {
+			errs = append(errs, lg.Write(&Abort{Tx: tx}))
+		}
+		return errors.Join(errs...)
+	}
+	var errs []error
+	for lg := range recs {
+		if err := lg.Write(&Commit{Tx: tx}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("transaction %s: %w: %v", tx, ErrInDoubt, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Resolve settles the transactions left in doubt in lgs, which must include
+// every log the transactions were appended to. Commits are only written
+// once every intent is, so a transaction with a Commit in any log is
+// committed in every log holding its intent but no Commit; a transaction
+// with no Commit anywhere is aborted in every log holding its intent but no
+// marker. It returns the number of markers written. Resolve is a recovery
+// step: it must not run while AppendAll calls on the same logs are in
+// progress.
+func Resolve(lgs ...Logger) (int, error) {
+	committed := make(map[string]bool)
+	// per log, the transactions with an intent and whether a marker follows
+	open := make([]map[string]bool, len(lgs))
+	var order []string // transactions in the order first seen
+	for i, lg := range lgs {
+		open[i] = make(map[string]bool)
+		for at := int64(0); at < lg.Len(); at++ {
+			v, err := lg.ReadAt(at)
+			if err != nil {
+				return 0, err
+			}
+			if in, ok := bare(v).(*Intent); ok {
+				if _, seen := open[i][in.Tx]; !seen {
+					order = append(order, in.Tx)
+				}
+				open[i][in.Tx] = true
+				continue
+			}
+			switch m := inner(v).(type) {
+			case *Commit:
+				committed[m.Tx] = true
+				open[i][m.Tx] = false
+			case *Abort:
+				open[i][m.Tx] = false
+			}
+		}
+	}
+	n := 0
+	done := make(map[string]bool)
+	for _, tx := range order {
+		if done[tx] {
+			continue
+		}
+		done[tx] = true
+		var m event.Record = &Abort{Tx: tx}
+		if committed[tx] {
+			m = &Commit{Tx: tx}
+		}
+		for i, lg := range lgs {
+			if !open[i][tx] {
+				continue
+			}
+			if err := lg.Write(m); err != nil {
+				return n, fmt.Errorf("resolve transaction %s: %w", tx, err)
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func txid() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// Committed returns a read-only view of lg holding its ordinary records and
+// the records of committed transactions, in the position of their intents.
+// Transaction markers and uncommitted intents are left out. Like Live, the
+// view is fixed when Committed is called.
+func Committed(lg Logger) (Logger, error) {
+	n := lg.Len()
+	committed := make(map[string]bool)
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := inner(v).(*Commit); ok {
+			committed[c.Tx] = true
+		}
+	}
+	view := &committedLog{refLog{lgs: []Logger{lg}}}
+	for at := int64(0); at < n; at++ {
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			return nil, err
+		}
+		switch inner(v).(type) {
+		case *Commit, *Abort:
+			continue
+		}
+		if i, ok := bare(v).(*Intent); ok && !committed[i.Tx] {
+			continue
+		}
+		view.refs = append(view.refs, ref{0, at})
+	}
+	return view, nil
+}
+
+// committedLog is a refLog returning the records inside intents
+type committedLog struct {
+	refLog
+}
+
+func (m *committedLog) ReadAt(n int64) (event.Record, error) {
+	v, err := m.refLog.ReadAt(n)
+	if i, ok := bare(v).(*Intent); ok {
+		return i.Record, err
+	}
+	return v, err
+}
@@ -0,0 +1,127 @@
+// Package undo provides an undo/redo stack backed by a worm.Logger.
+//
+// Records written to a Stack are appended to the underlying log and grouped
+// into undo units. A unit ends when Mark is called. When the Stack is the
+// logger given to worm.NewCoalescer, the coalescer marks the stack each time
+// its deadband expires, so one burst of coalesced edits becomes one unit.
+//
+//	stack := undo.NewStack(worm.NewLogger())
+//	edits := worm.NewCoalescer(stack, 500*time.Millisecond)
+//
+// The log itself is never rewritten. Undo and Redo only move the stack's
+// position and return the records of the unit that the caller must invert or
+// reapply.
+package undo
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/as/event"
+	"github.com/as/worm"
+)
+
+var (
+	ErrNoUndo = errors.New("undo: nothing to undo")
+	ErrNoRedo = errors.New("undo: nothing to redo")
+)
+
+// Stack is an undo/redo stack of records stored in a worm.Logger
+type Stack struct {
+	worm.Logger
+
+	mu    sync.Mutex
+	units []unit
+	top   int  // number of units currently applied
+	open  bool // writes extend units[top-1]
+}
+
+// unit is the half-open range of log records [lo, hi) making up one undo step
+type unit struct {
+	lo, hi int64
+}
+
+// NewStack returns an undo stack that appends to lg
+func NewStack(lg worm.Logger) *Stack {
+	return &Stack{Logger: lg}
+}
+
+// Write writes v to the tail of the log and adds it to the current unit.
+// Writing after Undo discards the units that could have been redone.
+func (s *Stack) Write(v event.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.open {
+		s.units = s.units[:s.top]
+		n := s.Logger.Len()
+		s.units = append(s.units, unit{n, n})
+		s.top++
+		s.open = true
+	}
+	err := s.Logger.Write(v)
+	s.units[s.top-1].hi = s.Logger.Len()
+	return err
+}
+
+// Mark ends the current unit. The next Write starts a new one.
+func (s *Stack) Mark() {
+	s.mu.Lock()
+	s.open = false
+	s.mu.Unlock()
+}
+
+// Undo steps back one unit and returns its records, most recent first
+func (s *Stack) Undo() ([]event.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open = false
+	if s.top == 0 {
+		return nil, ErrNoUndo
+	}
+	u := s.units[s.top-1]
+	rec := make([]event.Record, 0, u.hi-u.lo)
+	for n := u.hi - 1; n >= u.lo; n-- {
+		v, err := s.Logger.ReadAt(n)
+		if err != nil {
+			return nil, err
+		}
+		rec = append(rec, v)
+	}
+	s.top--
+	return rec, nil
+}
+
+// Redo steps forward one unit and returns its records in the order they
+// were written
+func (s *Stack) Redo() ([]event.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.top == len(s.units) {
+		return nil, ErrNoRedo
+	}
+	u := s.units[s.top]
+	rec := make([]event.Record, 0, u.hi-u.lo)
+	for n := u.lo; n < u.hi; n++ {
+		v, err := s.Logger.ReadAt(n)
+		if err != nil {
+			return nil, err
+		}
+		rec = append(rec, v)
+	}
+	s.top++
+	return rec, nil
+}
+
+// CanUndo reports whether Undo would return a unit
+func (s *Stack) CanUndo() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.top > 0
+}
+
+// CanRedo reports whether Redo would return a unit
+func (s *Stack) CanRedo() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.top < len(s.units)
+}
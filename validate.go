@@ -0,0 +1,65 @@
+package worm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/as/event"
+)
+
+// ErrTooLarge is wrapped by the ValidationError for an oversized record
+var ErrTooLarge = errors.New("record too large")
+
+// ValidationError is returned by a Validator when it rejects a record
+type ValidationError struct {
+	Record event.Record
+	Size   int   // encoded size, if the Validator has a size limit
+	Err    error // ErrTooLarge or the error returned by the check
+}
+
+func (e *ValidationError) Error() string {
+	if errors.Is(e.Err, ErrTooLarge) {
+		return fmt.Sprintf("reject %T: %d bytes: %v", e.Record, e.Size, e.Err)
+	}
+	return fmt.Sprintf("reject %T: %v", e.Record, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validator is a logger that rejects records before they reach storage
+type Validator struct {
+	Logger
+
+	max   int
+	check func(event.Record) error
+}
+
+// Validate wraps lg, rejecting records whose encoding is larger than
+// maxBytes or for which check returns an error. A maxBytes of zero or less
+// disables the size limit and a nil check accepts every record.
+func Validate(lg Logger, maxBytes int, check func(event.Record) error) *Validator {
+	return &Validator{Logger: lg, max: maxBytes, check: check}
+}
+
+// Write writes v to the tail of the log if it is valid, otherwise it
+// returns a *ValidationError
+func (l *Validator) Write(v event.Record) error {
+	size := 0
+	if l.max > 0 {
+		b, err := encode(v)
+		if err != nil {
+			return &ValidationError{Record: v, Err: err}
+		}
+		if size = len(b); size > l.max {
+			return &ValidationError{Record: v, Size: size, Err: ErrTooLarge}
+		}
+	}
+	if l.check != nil {
+		if err := l.check(v); err != nil {
+			return &ValidationError{Record: v, Size: size, Err: err}
+		}
+	}
+	return l.Logger.Write(v)
+}
@@ -0,0 +1,117 @@
+package worm
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Problem is a fault found by VerifyDeep
+type Problem struct {
+	N      int64 // record index, or the N of an anchor
+	Anchor bool  // the anchor at N failed, rather than record N
+	Err    string
+}
+
+// VerifyReport is the result of VerifyDeep, meant for encoding as JSON
+type VerifyReport struct {
+	Records  int64
+	Anchors  int
+	Head     Hash // chain head over every record, if all were readable
+	Problems []Problem
+}
+
+// OK reports whether the audit found no problems
+func (r *VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// VerifyDeep re-reads and re-hashes every record of lg using up to workers
+// goroutines and checks the hash chain against anchors. Unlike
+// VerifyAnchors it does not stop at the first problem, but reports every
+// unreadable record and every failed anchor. The error is only set if ctx
+// is done before the audit completes.
+func VerifyDeep(ctx context.Context, lg Logger, anchors []Anchor, workers int) (*VerifyReport, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	n := lg.Len()
+	r := &VerifyReport{Records: n, Anchors: len(anchors)}
+	sums := make([][sha256.Size]byte, n)
+	errs := make([]error, n)
+
+	work := make(chan int64)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for from := range work {
+				for at := from; at < from+chunk && at < n; at++ {
+					v, err := lg.ReadAt(at)
+					if err == nil {
+						sums[at], err = checksum(v)
+					}
+					errs[at] = err
+				}
+			}
+		}()
+	}
+	var err error
+	for from := int64(0); from < n; from += chunk {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		work <- from
+	}
+	close(work)
+	wg.Wait()
+	if err != nil {
+		return r, err
+	}
+
+	anchors = append([]Anchor(nil), anchors...)
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].N < anchors[j].N })
+	var (
+		head Hash
+		bad  = int64(-1) // first unreadable record
+		at   int64
+	)
+	fold := func(end int64) {
+		for ; at < end; at++ {
+			if errs[at] != nil {
+				r.Problems = append(r.Problems, Problem{N: at, Err: errs[at].Error()})
+				if bad < 0 {
+					bad = at
+				}
+			}
+			if bad < 0 {
+				head = sha256.Sum256(append(head[:], sums[at][:]...))
+			}
+		}
+	}
+	for _, an := range anchors {
+		if an.N <= n {
+			fold(an.N)
+		}
+		switch {
+		case an.N > n:
+			r.Problems = append(r.Problems, Problem{N: an.N, Anchor: true, Err: fmt.Sprintf("log has %d records: %v", n, ErrTampered)})
+		case bad >= 0:
+			r.Problems = append(r.Problems, Problem{N: an.N, Anchor: true, Err: fmt.Sprintf("record %d unreadable", bad)})
+		case head != an.Head:
+			r.Problems = append(r.Problems, Problem{N: an.N, Anchor: true, Err: ErrTampered.Error()})
+		}
+	}
+	fold(n)
+	if bad < 0 {
+		r.Head = head
+	}
+	for _, p := range r.Problems {
+		alert("verify deep", p.N, errors.New(p.Err))
+	}
+	return r, nil
+}
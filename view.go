@@ -0,0 +1,45 @@
+package worm
+
+import (
+	"fmt"
+
+	"github.com/as/event"
+)
+
+// View returns a read-only logger frozen at the current length of lg.
+// Records written to lg afterwards are not visible through the view, so a
+// replay or export sees a consistent prefix while writes continue. No
+// records are copied.
+func View(lg Logger) Logger {
+	if l, ok := lg.(*logWORM); ok {
+		// the slice header is enough, appends never touch this prefix
+		l.mu.RLock()
+		rec := l.rec[:len(l.rec):len(l.rec)]
+		l.mu.RUnlock()
+		return &view{Logger: &logWORM{rec: rec}, n: int64(len(rec))}
+	}
+	return &view{Logger: lg, n: lg.Len()}
+}
+
+type view struct {
+	Logger
+	n int64
+}
+
+func (v *view) Write(event.Record) error {
+	return ErrReadOnly
+}
+
+// ReadAt reads and returns log record n
+func (v *view) ReadAt(n int64) (event.Record, error) {
+	at := fromTail(n, v.n)
+	if at < 0 || at >= v.n {
+		return nil, fmt.Errorf("bad read offset: %d", n)
+	}
+	return v.Logger.ReadAt(at)
+}
+
+// Len returns the number of records in the view
+func (v *view) Len() int64 {
+	return v.n
+}
@@ -0,0 +1,156 @@
+// Package wormtest provides helpers for using worm logs as test fixtures.
+//
+// A test records the log produced by the code under test and compares it to
+// a golden file with AssertGolden. Running the test with -wormtest.update
+// rewrites the golden file instead.
+//
+// Golden files hold one JSON object per line with the record's Go type and
+// its JSON encoding, so they diff well in review.
+package wormtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/as/event"
+	"github.com/as/worm"
+)
+
+var update = flag.Bool("wormtest.update", false, "rewrite golden log fixtures")
+
+// maxDiffs is the number of mismatched records reported before giving up
+const maxDiffs = 10
+
+// Line is one record of a golden file
+type Line struct {
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+}
+
+func line(v event.Record) (Line, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Line{}, err
+	}
+	return Line{Type: fmt.Sprintf("%T", v), Record: b}, nil
+}
+
+func (l Line) String() string {
+	return l.Type + " " + string(l.Record)
+}
+
+// WriteGolden writes the records of lg to the golden file at path,
+// creating its directory if needed
+func WriteGolden(path string, lg worm.Logger) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for n := int64(0); n < lg.Len(); n++ {
+		v, err := lg.ReadAt(n)
+		if err != nil {
+			return err
+		}
+		l, err := line(v)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if err = enc.Encode(l); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// ReadGolden returns the lines of the golden file at path
+func ReadGolden(path string) ([]Line, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var list []Line
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for sc.Scan() {
+		var l Line
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			return list, fmt.Errorf("%s:%d: %w", path, len(list)+1, err)
+		}
+		list = append(list, l)
+	}
+	return list, sc.Err()
+}
+
+// AssertGolden fails t unless lg holds the same records as the golden file
+// at path, reporting each mismatched record. With -wormtest.update it
+// rewrites the file from lg instead.
+func AssertGolden(t testing.TB, path string, lg worm.Logger) {
+	t.Helper()
+	if *update {
+		if err := WriteGolden(path, lg); err != nil {
+			t.Fatalf("update golden log: %v", err)
+		}
+		return
+	}
+	want, err := ReadGolden(path)
+	if err != nil {
+		t.Fatalf("read golden log: %v (run with -wormtest.update to create it)", err)
+	}
+	var diffs []string
+	n := lg.Len()
+	for at := int64(0); at < n || at < int64(len(want)); at++ {
+		if len(diffs) == maxDiffs {
+			diffs = append(diffs, "...")
+			break
+		}
+		if at >= n {
+			diffs = append(diffs, fmt.Sprintf("record %d: missing, want %v", at, want[at]))
+			continue
+		}
+		v, err := lg.ReadAt(at)
+		if err != nil {
+			t.Fatalf("read record %d: %v", at, err)
+		}
+		got, err := line(v)
+		if err != nil {
+			t.Fatalf("encode record %d: %v", at, err)
+		}
+		if at >= int64(len(want)) {
+			diffs = append(diffs, fmt.Sprintf("record %d: unexpected %v", at, got))
+		} else if got.String() != want[at].String() {
+			diffs = append(diffs, fmt.Sprintf("record %d:\n\tgot  %v\n\twant %v", at, got, want[at]))
+		}
+	}
+	if len(diffs) > 0 {
+		t.Errorf("log differs from %s (%d records, want %d):\n%s", path, n, len(want), strings.Join(diffs, "\n"))
+	}
+}
+
+// Load replays the golden file at path into a new in-memory log. Decode
+// turns each line back into a record.
+func Load(path string, decode func(Line) (event.Record, error)) (worm.Logger, error) {
+	list, err := ReadGolden(path)
+	if err != nil {
+		return nil, err
+	}
+	lg := worm.NewLogger()
+	for i, l := range list {
+		v, err := decode(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		if err = lg.Write(v); err != nil {
+			return nil, err
+		}
+	}
+	return lg, nil
+}